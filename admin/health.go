@@ -0,0 +1,105 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// checkTimeout adalah batas waktu untuk satu pemeriksaan readiness.
+const checkTimeout = 5 * time.Second
+
+// Check adalah satu pemeriksaan dependensi untuk readiness probe.
+type Check struct {
+	Name string
+	Func func(ctx context.Context) error
+}
+
+// Health menyediakan endpoint liveness (/healthz) dan readiness (/readyz).
+type Health struct {
+	mu           sync.RWMutex
+	checks       []Check
+	loaded       bool // Komponen sudah dimuat dan daftar pemeriksaan sudah di-set
+	shuttingDown atomic.Bool
+}
+
+// checkResult adalah hasil satu pemeriksaan di response /readyz.
+type checkResult struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// NewHealth membuat Health yang belum siap sampai SetChecks dipanggil, sehingga
+// /readyz mengembalikan 503 selama komponen masih dimuat saat startup.
+func NewHealth() *Health {
+	return &Health{}
+}
+
+// MarkShuttingDown membuat readiness gagal agar Kubernetes berhenti mengirim traffic
+// selama graceful shutdown.
+func (h *Health) MarkShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// SetChecks menandai komponen sudah dimuat dan mengganti daftar pemeriksaan readiness,
+// misalnya setelah startup selesai atau konfigurasi di-reload.
+func (h *Health) SetChecks(checks ...Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = checks
+	h.loaded = true
+}
+
+// Liveness selalu mengembalikan 200 selama proses masih bisa melayani HTTP.
+func (h *Health) Liveness(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("ok\n"))
+}
+
+// Readiness menjalankan semua pemeriksaan secara paralel dan mengembalikan 503
+// jika komponen belum dimuat, ada pemeriksaan yang gagal atau server sedang shutdown.
+func (h *Health) Readiness(w http.ResponseWriter, req *http.Request) {
+	h.mu.RLock()
+	checks, loaded := h.checks, h.loaded
+	h.mu.RUnlock()
+
+	results := make([]checkResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(req.Context(), checkTimeout)
+			defer cancel()
+
+			results[i] = checkResult{Name: check.Name, OK: true}
+			if err := check.Func(ctx); err != nil {
+				results[i] = checkResult{Name: check.Name, Error: err.Error()}
+			}
+		}()
+	}
+	wg.Wait()
+
+	ready := loaded && !h.shuttingDown.Load()
+	for _, r := range results {
+		ready = ready && r.OK
+	}
+
+	status := http.StatusOK
+	if !ready {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(struct {
+		Ready        bool          `json:"ready"`
+		Loaded       bool          `json:"loaded"`
+		ShuttingDown bool          `json:"shutting_down"`
+		Checks       []checkResult `json:"checks"`
+	}{ready, loaded, h.shuttingDown.Load(), results})
+}
@@ -0,0 +1,81 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// readiness memanggil /readyz dan mengembalikan status serta body JSON-nya.
+func readiness(t *testing.T, h *Health) (int, map[string]any) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	NewHandler(h).ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body /readyz bukan JSON yang valid: %v (%q)", err, rec.Body.String())
+	}
+	return rec.Code, body
+}
+
+// liveness memanggil /healthz dan mengembalikan statusnya.
+func liveness(h *Health) int {
+	rec := httptest.NewRecorder()
+	NewHandler(h).ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+	return rec.Code
+}
+
+func TestReadinessLifecycle(t *testing.T) {
+	ok := Check{Name: "waf", Func: func(ctx context.Context) error { return nil }}
+	h := NewHealth()
+
+	// Sebelum komponen dimuat
+	status, body := readiness(t, h)
+	if status != http.StatusServiceUnavailable || body["ready"] != false || body["loaded"] != false {
+		t.Errorf("sebelum komponen dimuat: status %d, body %v, seharusnya 503 dan loaded=false", status, body)
+	}
+	if got := liveness(h); got != http.StatusOK {
+		t.Errorf("/healthz sebelum komponen dimuat = %d, seharusnya 200", got)
+	}
+
+	// Setelah komponen dimuat
+	h.SetChecks(ok)
+	if status, body := readiness(t, h); status != http.StatusOK || body["ready"] != true {
+		t.Errorf("setelah komponen dimuat: status %d, body %v, seharusnya 200", status, body)
+	}
+
+	// Selama draining, readiness gagal walaupun semua pemeriksaan berhasil
+	h.MarkShuttingDown()
+	status, body = readiness(t, h)
+	if status != http.StatusServiceUnavailable || body["shutting_down"] != true {
+		t.Errorf("selama draining: status %d, body %v, seharusnya 503 dan shutting_down=true", status, body)
+	}
+	if got := liveness(h); got != http.StatusOK {
+		t.Errorf("/healthz selama draining = %d, seharusnya 200", got)
+	}
+}
+
+func TestReadinessFailingCheck(t *testing.T) {
+	h := NewHealth()
+	h.SetChecks(
+		Check{Name: "waf", Func: func(ctx context.Context) error { return nil }},
+		Check{Name: "backend", Func: func(ctx context.Context) error { return errors.New("connection refused") }},
+	)
+
+	status, body := readiness(t, h)
+	if status != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, seharusnya 503", status)
+	}
+	checks, _ := body["checks"].([]any)
+	if len(checks) != 2 {
+		t.Fatalf("checks = %v, seharusnya 2 pemeriksaan", body["checks"])
+	}
+	backend, _ := checks[1].(map[string]any)
+	if backend["name"] != "backend" || backend["ok"] != false || backend["error"] != "connection refused" {
+		t.Errorf("hasil pemeriksaan backend = %v", backend)
+	}
+}
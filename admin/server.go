@@ -0,0 +1,17 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/luhtaf/corator/metrics"
+)
+
+// NewHandler membuat handler untuk listener admin yang terpisah dari traffic proxy,
+// sehingga endpoint internal tidak terekspos ke client.
+func NewHandler(health *Health) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Handler())
+	mux.HandleFunc("GET /healthz", health.Liveness)
+	mux.HandleFunc("GET /readyz", health.Readiness)
+	return mux
+}
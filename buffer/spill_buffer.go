@@ -0,0 +1,102 @@
+package buffer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/luhtaf/corator/config"
+)
+
+// SpillBuffer menampung data di memori hingga batas tertentu, lalu memindahkan
+// (spill) seluruh isinya ke file sementara. Setelah selesai ditulis, isinya bisa
+// dibaca berkali-kali lewat Reader tanpa menyalin ulang ke memori.
+type SpillBuffer struct {
+	memLimit int64
+	dir      string
+	mem      bytes.Buffer
+	file     *os.File
+	size     int64
+}
+
+// New membuat SpillBuffer baru dengan batas memori dan direktori file sementara.
+// Direktori kosong berarti menggunakan os.TempDir().
+func New(memLimit int64, dir string) *SpillBuffer {
+	return &SpillBuffer{memLimit: memLimit, dir: dir}
+}
+
+// NewFromConfig membuat SpillBuffer baru berdasarkan konfigurasi buffer.
+func NewFromConfig(cfg config.BufferConfig) *SpillBuffer {
+	return New(cfg.MemoryLimit, cfg.TempDir)
+}
+
+// Write menambahkan data ke buffer, memindahkan isi ke disk jika batas memori terlampaui.
+func (b *SpillBuffer) Write(p []byte) (int, error) {
+	if b.file == nil && int64(b.mem.Len()+len(p)) > b.memLimit {
+		if err := b.spill(); err != nil {
+			return 0, err
+		}
+	}
+
+	var (
+		n   int
+		err error
+	)
+	if b.file != nil {
+		n, err = b.file.Write(p)
+	} else {
+		n, err = b.mem.Write(p)
+	}
+	b.size += int64(n)
+	return n, err
+}
+
+// spill memindahkan isi memori ke file sementara.
+func (b *SpillBuffer) spill() error {
+	file, err := os.CreateTemp(b.dir, "corator-*.buf")
+	if err != nil {
+		return fmt.Errorf("gagal membuat file buffer sementara: %w", err)
+	}
+	if _, err := file.Write(b.mem.Bytes()); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return fmt.Errorf("gagal menulis ke file buffer sementara: %w", err)
+	}
+	b.file = file
+	b.mem = bytes.Buffer{}
+	return nil
+}
+
+// Size mengembalikan jumlah byte yang sudah ditulis.
+func (b *SpillBuffer) Size() int64 {
+	return b.size
+}
+
+// Reader mengembalikan reader baru yang membaca isi buffer dari awal.
+// Setiap reader independen sehingga aman dipakai oleh beberapa konsumen.
+func (b *SpillBuffer) Reader() *io.SectionReader {
+	if b.file != nil {
+		return io.NewSectionReader(b.file, 0, b.size)
+	}
+	return io.NewSectionReader(bytes.NewReader(b.mem.Bytes()), 0, b.size)
+}
+
+// Head mengembalikan maksimal n byte pertama, misalnya untuk content sniffing.
+func (b *SpillBuffer) Head(n int) []byte {
+	head := make([]byte, n)
+	read, _ := io.ReadFull(b.Reader(), head)
+	return head[:read]
+}
+
+// Close melepaskan memori dan menghapus file sementara jika ada.
+func (b *SpillBuffer) Close() error {
+	b.mem = bytes.Buffer{}
+	if b.file == nil {
+		return nil
+	}
+	name := b.file.Name()
+	b.file.Close()
+	b.file = nil
+	return os.Remove(name)
+}
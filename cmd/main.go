@@ -1,59 +1,261 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
+	"maps"
 	"net/http"
-	"net/url"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
 
+	"github.com/luhtaf/corator/admin"
 	"github.com/luhtaf/corator/config"
-	"github.com/luhtaf/corator/detector"
 	"github.com/luhtaf/corator/handler"
-	"github.com/luhtaf/corator/logger"
-	"github.com/luhtaf/corator/uploader"
-	"github.com/luhtaf/corator/waf"
+	"github.com/luhtaf/corator/metrics"
+	"github.com/luhtaf/corator/spool"
+	"github.com/luhtaf/corator/tlsserver"
+	"github.com/luhtaf/corator/upstream"
+	"github.com/luhtaf/corator/worker"
 )
 
 func main() {
+	// Subcommand "validate" memeriksa konfigurasi tanpa menjalankan server
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(runValidate(os.Args[2:]))
+	}
+
+	configPath := flag.String("config", "", "path file konfigurasi YAML/TOML/JSON (default: env CORATOR_CONFIG)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Penggunaan:\n  %[1]s [-config file]\n  %[1]s validate [-config file] [-timeout durasi] [-quiet]\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
 	log.Println("Memulai Corator WAF Interceptor...")
 
 	// 1. Muat Konfigurasi
-	cfg, err := config.LoadConfig()
+	cfg, err := config.LoadConfig(*configPath)
 	if err != nil {
 		log.Fatalf("Gagal memuat konfigurasi: %v", err)
 	}
 
-	// 2. Inisialisasi semua komponen via factory
-	loggers := logger.NewLoggers(&cfg)
+	// Server admin dijalankan lebih dulu agar liveness bisa dijawab selama komponen
+	// dimuat; readiness baru berhasil setelah semua listener siap
+	health := admin.NewHealth()
 
-	uploader, err := uploader.NewUploader(&cfg)
+	var adminServer *http.Server
+	if cfg.Admin.ListenAddress != "" {
+		adminServer = &http.Server{
+			Addr:    cfg.Admin.ListenAddress,
+			Handler: admin.NewHandler(health),
+		}
+		go func() {
+			if err := adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("PERINGATAN: Server admin gagal berjalan: %v", err)
+			}
+		}()
+		log.Printf("Server admin (/metrics, /healthz, /readyz) berjalan di %s", cfg.Admin.ListenAddress)
+	}
+
+	// 2. Inisialisasi semua komponen via factory
+	comps, err := buildComponents(&cfg)
 	if err != nil {
-		log.Fatalf("Gagal membuat uploader: %v", err)
+		log.Fatalf("%v", err)
 	}
+	if comps.loggerErr != nil {
+		log.Printf("PERINGATAN: %v", comps.loggerErr)
+	}
+	comps.start()
 
-	waf, err := waf.NewWAF(cfg.WAF)
+	evidenceSpool, err := spool.New(cfg.Spool, comps.uploaders, comps.loggers)
 	if err != nil {
-		log.Fatalf("Gagal membuat WAF: %v", err)
+		log.Fatalf("Gagal membuat spool: %v", err)
 	}
 
-	detectors := detector.NewDetectors(&cfg)
+	// Context ini dibatalkan saat menerima SIGTERM (Kubernetes) atau SIGINT
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	go evidenceSpool.Run(ctx)
 
-	backendURL, err := url.Parse(cfg.Server.BackendURL)
+	workers, err := worker.NewPool(cfg.Worker)
 	if err != nil {
-		log.Fatalf("URL Backend tidak valid: %v", err)
+		log.Fatalf("Gagal membuat worker pool: %v", err)
 	}
 
 	// 3. Buat handler utama dan suntikkan semua komponen
-	mainHandler := handler.NewRequestHandler(waf, detectors, uploader, loggers, backendURL)
+	mainHandler := handler.NewRequestHandler(comps.handler, evidenceSpool, workers)
+
+	// Daftarkan metrik antrean worker dan spool
+	registerQueueMetrics(workers, evidenceSpool)
+
+	// 4. Siapkan listener HTTP dan, jika dikonfigurasi, HTTPS
+	proxyHandler := metrics.InstrumentHandler(mainHandler)
+
+	var certManager *tlsserver.Manager
+	if cfg.TLS.ListenAddress != "" {
+		certManager, err = tlsserver.New(cfg.TLS)
+		if err != nil {
+			log.Fatalf("Gagal memuat konfigurasi TLS: %v", err)
+		}
+		go certManager.Run(ctx)
+	}
+
+	var servers []*http.Server
+	var httpServer, tlsServer *http.Server
+	if cfg.Server.ListenAddress != "" {
+		httpServer = &http.Server{
+			Addr:    cfg.Server.ListenAddress,
+			Handler: proxyHandler,
+		}
+		if certManager != nil {
+			// Listener HTTP juga menjawab challenge ACME HTTP-01
+			httpServer.Handler = certManager.HTTPHandler(proxyHandler)
+		}
+		servers = append(servers, httpServer)
+	}
+	if certManager != nil {
+		tlsServer = &http.Server{
+			Addr:      cfg.TLS.ListenAddress,
+			Handler:   proxyHandler,
+			TLSConfig: certManager.TLSConfig(),
+		}
+		servers = append(servers, tlsServer)
+	}
+
+	// Reload konfigurasi lewat SIGHUP atau saat file yang dipantau berubah
+	if *configPath == "" {
+		*configPath = os.Getenv("CORATOR_CONFIG")
+	}
+	configReloader := &reloader{
+		configPath: *configPath,
+		handler:    mainHandler,
+		spool:      evidenceSpool,
+		health:     health,
+		comps:      comps,
+		started:    cfg,
+		cfg:        cfg,
+	}
+	go configReloader.Run(ctx)
+	if cfg.Reload.WatchInterval > 0 {
+		log.Printf("Memantau perubahan file konfigurasi setiap %s", cfg.Reload.WatchInterval)
+	}
+
+	redacted := cfg.Redacted()
+	log.Printf("Meneruskan traffic ke backend: %s", upstream.DefaultKey(&redacted))
 
-	// 4. Jalankan server HTTP
-	server := &http.Server{
-		Addr:    cfg.Server.ListenAddress,
-		Handler: mainHandler,
+	serverErr := make(chan error, len(servers))
+	if httpServer != nil {
+		log.Printf("Server HTTP berjalan di %s", cfg.Server.ListenAddress)
+		go func() {
+			serverErr <- httpServer.ListenAndServe()
+		}()
+	}
+	if tlsServer != nil {
+		log.Printf("Server HTTPS berjalan di %s", cfg.TLS.ListenAddress)
+		go func() {
+			// Sertifikat diambil dari TLSConfig.GetCertificate
+			serverErr <- tlsServer.ListenAndServeTLS("", "")
+		}()
 	}
 
-	log.Printf("Server berjalan di %s", cfg.Server.ListenAddress)
-	log.Printf("Meneruskan traffic ke backend: %s", backendURL)
-	if err := server.ListenAndServe(); err != nil {
+	health.SetChecks(healthChecks(comps, &cfg)...)
+
+	select {
+	case err := <-serverErr:
 		log.Fatalf("Server gagal berjalan: %v", err)
+	case <-ctx.Done():
+	}
+
+	// Readiness langsung gagal agar load balancer berhenti mengirim traffic baru
+	health.MarkShuttingDown()
+
+	// 5. Graceful shutdown: selesaikan request yang berjalan, lalu tunggu
+	// semua file bukti selesai diunggah dan dicatat
+	log.Printf("Menerima sinyal shutdown, menunggu proses selesai (maksimal %s)...", cfg.Server.DrainTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.DrainTimeout)
+	defer cancel()
+
+	for _, server := range servers {
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("PERINGATAN: Gagal menutup server %s dengan bersih: %v", server.Addr, err)
+		}
+	}
+	if err := workers.Shutdown(shutdownCtx); err != nil {
+		log.Printf("PERINGATAN: %v", err)
+	}
+	if adminServer != nil {
+		adminServer.Shutdown(shutdownCtx)
 	}
+
+	// Laporan akhir untuk bukti yang belum terkirim
+	stats := workers.Stats()
+	pendingUploads, pendingEvents := evidenceSpool.Depth()
+	log.Printf("Shutdown selesai: %d file diproses, %d disimpan ke spool, %d dibuang, %d terputus di tengah proses",
+		stats.Completed, stats.Spilled, stats.Dropped, stats.Active)
+	if pendingUploads > 0 || pendingEvents > 0 {
+		log.Printf("Spool berisi %d upload dan %d log event yang akan dikirim ulang saat Corator berjalan kembali",
+			pendingUploads, pendingEvents)
+	}
+}
+
+// healthChecks menyusun pemeriksaan readiness untuk setiap WAF, setiap uploader, setiap
+// logger dan setiap upstream. Upstream default diberi nama "backend".
+func healthChecks(c *components, cfg *config.Config) []admin.Check {
+	var checks []admin.Check
+	for _, path := range slices.Sorted(maps.Keys(c.wafs)) {
+		corazaWAF := c.wafs[path]
+		name := "waf"
+		if path != cfg.WAF.CorazaConfigPath {
+			name = "waf:" + path
+		}
+		checks = append(checks, admin.Check{
+			Name: name,
+			Func: func(ctx context.Context) error {
+				return corazaWAF.NewTransaction().Close()
+			},
+		})
+	}
+	for _, up := range c.uploaders {
+		checks = append(checks, admin.Check{Name: "uploader:" + up.Name(), Func: up.Check})
+	}
+	for _, l := range c.loggers {
+		checks = append(checks, admin.Check{Name: "logger:" + l.Name(), Func: l.Check})
+	}
+	for _, key := range slices.Sorted(maps.Keys(c.pools)) {
+		name := "upstream:" + key
+		if key == upstream.DefaultKey(cfg) {
+			name = "backend"
+		}
+		checks = append(checks, admin.Check{Name: name, Func: c.pools[key].Check})
+	}
+	return checks
+}
+
+// registerQueueMetrics mendaftarkan metrik kedalaman antrean worker pool dan spool.
+func registerQueueMetrics(workers *worker.Pool, evidenceSpool *spool.Spool) {
+	metrics.RegisterGaugeFunc("worker_queue_depth", "Jumlah file bukti yang menunggu worker.", func() float64 {
+		return float64(workers.Stats().Queued)
+	})
+	metrics.RegisterGaugeFunc("worker_active", "Jumlah worker yang sedang memproses file bukti.", func() float64 {
+		return float64(workers.Stats().Active)
+	})
+	metrics.RegisterCounterFunc("worker_dropped_total", "Jumlah file bukti yang dibuang karena antrean penuh.", func() float64 {
+		return float64(workers.Stats().Dropped)
+	})
+	metrics.RegisterCounterFunc("worker_spilled_total", "Jumlah file bukti yang dialihkan ke spool karena antrean penuh.", func() float64 {
+		return float64(workers.Stats().Spilled)
+	})
+	metrics.RegisterGaugeFunc("spool_uploads_depth", "Jumlah upload yang tertunda di spool.", func() float64 {
+		uploads, _ := evidenceSpool.Depth()
+		return float64(uploads)
+	})
+	metrics.RegisterGaugeFunc("spool_events_depth", "Jumlah log event yang tertunda di spool.", func() float64 {
+		_, events := evidenceSpool.Depth()
+		return float64(events)
+	})
 }
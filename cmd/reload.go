@@ -0,0 +1,371 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/corazawaf/coraza/v3"
+	"github.com/luhtaf/corator/admin"
+	"github.com/luhtaf/corator/config"
+	"github.com/luhtaf/corator/detector"
+	"github.com/luhtaf/corator/extractor"
+	"github.com/luhtaf/corator/handler"
+	"github.com/luhtaf/corator/logger"
+	"github.com/luhtaf/corator/metrics"
+	"github.com/luhtaf/corator/policy"
+	"github.com/luhtaf/corator/spool"
+	"github.com/luhtaf/corator/uploader"
+	"github.com/luhtaf/corator/upstream"
+	"github.com/luhtaf/corator/waf"
+)
+
+// validateTimeout adalah batas waktu pemeriksaan uploader dan logger saat reload.
+const validateTimeout = 10 * time.Second
+
+// components adalah semua komponen yang dibangun dari satu versi konfigurasi.
+type components struct {
+	handler   *handler.Components
+	wafs      map[string]coraza.WAF     // WAF global dan rule set khusus route, per path konfigurasi
+	pools     map[string]*upstream.Pool // Upstream per nama atau URL backend
+	uploaders []uploader.Uploader       // Elemen pertama adalah uploader default
+	loggers   []logger.Logger
+
+	// loggerErr berisi logger yang gagal diinisialisasi. Saat startup hanya menjadi
+	// peringatan, tetapi membuat reload dan subcommand validate gagal.
+	loggerErr error
+}
+
+// buildComponents membangun WAF, detektor, uploader, logger, route policy, extractor
+// serta halaman blokir dan error dari konfigurasi. Logger yang sudah dibuka ditutup
+// kembali jika komponen lain gagal dibangun.
+func buildComponents(cfg *config.Config) (_ *components, err error) {
+	loggers, loggerErr := logger.OpenLoggers(cfg)
+	defer func() {
+		if err != nil {
+			logger.CloseLoggers(loggers)
+		}
+	}()
+
+	// Uploader pertama adalah default, sisanya dipakai oleh route policy
+	uploaders, err := uploader.NewUploaders(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("gagal membuat uploader: %w", err)
+	}
+
+	wafs, err := waf.NewWAFs(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("gagal membuat WAF: %w", err)
+	}
+
+	pools, err := upstream.NewPools(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("gagal membuat upstream: %w", err)
+	}
+
+	detectors := detector.NewDetectors(cfg)
+
+	detectorNames := make([]string, 0, len(detectors))
+	for _, d := range detectors {
+		detectorNames = append(detectorNames, d.Name())
+	}
+	routePolicy, err := policy.New(cfg.Policy, detectorNames, uploaders, wafs, pools)
+	if err != nil {
+		return nil, fmt.Errorf("gagal memuat route policy: %w", err)
+	}
+
+	var archiveExtractor *extractor.Extractor
+	if cfg.Archive.Enable {
+		log.Println("Ekstraksi archive aktif.")
+		archiveExtractor = extractor.New(cfg.Archive, cfg.Buffer)
+	}
+
+	blockPage, err := handler.NewBlockPage(cfg.WAF.BlockPage)
+	if err != nil {
+		return nil, fmt.Errorf("gagal memuat halaman blokir: %w", err)
+	}
+
+	errorPage, err := handler.NewErrorPage(cfg.Proxy.ErrorPage)
+	if err != nil {
+		return nil, fmt.Errorf("gagal memuat halaman error proxy: %w", err)
+	}
+
+	trustedProxies, err := handler.ParseTrustedProxies(cfg.Proxy.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	return &components{
+		handler: &handler.Components{
+			WAF:              wafs[cfg.WAF.CorazaConfigPath],
+			Detectors:        detectors,
+			Uploader:         uploaders[0],
+			Extractor:        archiveExtractor,
+			Policy:           routePolicy,
+			BlockPage:        blockPage,
+			BufferCfg:        cfg.Buffer,
+			ExportDetections: cfg.WAF.ExportDetections,
+			Upstream:         pools[upstream.DefaultKey(cfg)],
+			ErrorPage:        errorPage,
+			TrustedProxies:   trustedProxies,
+		},
+		wafs:      wafs,
+		pools:     pools,
+		uploaders: uploaders,
+		loggers:   loggers,
+		loggerErr: loggerErr,
+	}, nil
+}
+
+// start menjalankan health check aktif setiap upstream.
+func (c *components) start() {
+	for _, pool := range c.pools {
+		pool.Start()
+	}
+}
+
+// close menghentikan health check aktif setiap upstream dan menutup logger. Request
+// yang sedang berjalan tetap bisa memakai pool-nya hingga selesai; event yang gagal
+// dicatat oleh logger yang sudah ditutup dikirim ulang lewat spool.
+func (c *components) close() {
+	upstream.ClosePools(c.pools)
+	if err := logger.CloseLoggers(c.loggers); err != nil {
+		log.Printf("PERINGATAN: %v", err)
+	}
+}
+
+// validate memastikan semua logger yang diaktifkan berhasil dibuat dan semua uploader
+// serta logger bisa dijangkau, sebelum komponen baru menggantikan yang lama.
+func (c *components) validate(ctx context.Context) error {
+	errs := []error{c.loggerErr}
+
+	ctx, cancel := context.WithTimeout(ctx, validateTimeout)
+	defer cancel()
+	for _, up := range c.uploaders {
+		if err := up.Check(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("uploader %s: %w", up.Name(), err))
+		}
+	}
+	for _, l := range c.loggers {
+		if err := l.Check(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("logger %s: %w", l.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// reloader membangun ulang komponen dari konfigurasi saat menerima SIGHUP atau saat
+// file konfigurasi berubah, lalu menukarnya ke handler secara atomik. Request yang
+// sedang berjalan selesai dengan komponen lama; reload yang gagal mempertahankan
+// komponen lama.
+type reloader struct {
+	configPath string
+	handler    *handler.RequestHandler
+	spool      *spool.Spool
+	health     *admin.Health
+	comps      *components // Komponen yang sedang aktif
+
+	// started adalah konfigurasi saat startup, yang dipakai listener, worker pool dan
+	// spool hingga restart.
+	started config.Config
+
+	mu  sync.Mutex
+	cfg config.Config
+}
+
+// Reload memuat ulang konfigurasi dan menukar komponen jika semuanya valid.
+func (r *reloader) Reload(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.reload(ctx)
+	metrics.ObserveReload(err)
+	return err
+}
+
+func (r *reloader) reload(ctx context.Context) error {
+	cfg, err := config.LoadConfig(r.configPath)
+	if err != nil {
+		return err
+	}
+
+	if err := checkClientAuth(r.started.TLS, cfg); err != nil {
+		return err
+	}
+
+	c, err := buildComponents(&cfg)
+	if err != nil {
+		return err
+	}
+	if err := c.validate(ctx); err != nil {
+		c.close()
+		return err
+	}
+
+	if err := r.spool.SetTargets(c.uploaders, c.loggers); err != nil {
+		c.close()
+		return err
+	}
+	c.start()
+	r.handler.Swap(c.handler)
+	r.health.SetChecks(healthChecks(c, &cfg)...)
+	r.comps.close()
+	r.comps = c
+
+	// Dibandingkan dengan konfigurasi startup agar peringatan terus muncul selama
+	// perubahannya belum berlaku
+	if changed := restartOnlyChanges(r.started, cfg); len(changed) > 0 {
+		log.Printf("PERINGATAN: Perubahan pada %s baru berlaku setelah restart", strings.Join(changed, ", "))
+	}
+	if running := r.started.TLS; running.ClientAuth != cfg.TLS.ClientAuth || running.ClientCAFile != cfg.TLS.ClientCAFile {
+		log.Printf("PERINGATAN: Listener HTTPS masih memakai TLS_CLIENT_AUTH=%s dan TLS_CLIENT_CA_FILE=%q; mTLS baru berubah setelah restart",
+			running.ClientAuth, running.ClientCAFile)
+	}
+	r.cfg = cfg
+	return nil
+}
+
+// restartOnlyChanges mengembalikan bagian konfigurasi yang berubah tetapi tidak bisa
+// diterapkan tanpa restart, karena listener, worker pool dan spool sudah berjalan.
+// Pengaturan TLS dipakai listener HTTPS sejak startup; hanya isi file sertifikat dan
+// CA client yang dimuat ulang oleh tlsserver.Manager.
+func restartOnlyChanges(old, cfg config.Config) []string {
+	var changed []string
+	sections := []struct {
+		name     string
+		old, new any
+	}{
+		{"SERVER", restartOnlyServer(old.Server), restartOnlyServer(cfg.Server)},
+		{"TLS", old.TLS, cfg.TLS},
+		{"ADMIN", old.Admin, cfg.Admin},
+		{"SPOOL", old.Spool, cfg.Spool},
+		{"WORKER", old.Worker, cfg.Worker},
+		{"RELOAD", old.Reload, cfg.Reload},
+	}
+	for _, s := range sections {
+		if !reflect.DeepEqual(s.old, s.new) {
+			changed = append(changed, s.name)
+		}
+	}
+	return changed
+}
+
+// checkClientAuth menolak konfigurasi dengan route yang mewajibkan sertifikat client
+// jika listener HTTPS yang sedang berjalan tidak memverifikasinya. Mode verifikasi client
+// baru berubah setelah restart, jadi route tersebut akan menolak semua request.
+func checkClientAuth(running config.TLSConfig, cfg config.Config) error {
+	if running.ListenAddress != "" && running.ClientAuth != "none" {
+		return nil
+	}
+	for i, route := range cfg.Policy.Routes {
+		if !route.RequireClientCert {
+			continue
+		}
+		name := route.Name
+		if name == "" {
+			name = fmt.Sprintf("route-%d", i+1)
+		}
+		return fmt.Errorf("route %s membutuhkan sertifikat client, tetapi listener HTTPS yang berjalan tidak memverifikasinya; restart agar TLS_CLIENT_AUTH berlaku", name)
+	}
+	return nil
+}
+
+// restartOnlyServer mengosongkan pengaturan backend default yang bisa di-reload,
+// sehingga hanya perubahan listener yang dilaporkan.
+func restartOnlyServer(server config.ServerConfig) config.ServerConfig {
+	server.BackendURL = ""
+	server.Upstream = ""
+	return server
+}
+
+// Run menjalankan reload setiap kali menerima SIGHUP dan, jika WatchInterval diisi,
+// setiap kali file konfigurasi, rule Coraza, policy atau template halaman blokir dan error berubah.
+func (r *reloader) Run(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	r.mu.Lock()
+	interval := r.cfg.Reload.WatchInterval
+	r.mu.Unlock()
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	lastSeen := r.fingerprint()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			log.Println("Menerima SIGHUP, memuat ulang konfigurasi...")
+		case <-tick:
+			if r.fingerprint() == lastSeen {
+				continue
+			}
+			log.Println("File konfigurasi berubah, memuat ulang konfigurasi...")
+		}
+
+		if err := r.Reload(ctx); err != nil {
+			log.Printf("PERINGATAN: Reload gagal, konfigurasi lama tetap dipakai: %v", err)
+		} else {
+			log.Println("Konfigurasi berhasil dimuat ulang.")
+		}
+		// File yang gagal dimuat tidak dicoba lagi sampai berubah kembali
+		lastSeen = r.fingerprint()
+	}
+}
+
+// fingerprint merangkum ukuran dan waktu modifikasi semua file yang dipantau.
+// Seluruh isi direktori konfigurasi Coraza (global dan per route) ikut dipantau agar
+// perubahan pada file rule yang di-Include juga terdeteksi.
+func (r *reloader) fingerprint() string {
+	r.mu.Lock()
+	cfg := r.cfg
+	r.mu.Unlock()
+
+	paths := []string{
+		r.configPath, cfg.Policy.File,
+		cfg.WAF.BlockPage.HTMLTemplatePath, cfg.WAF.BlockPage.JSONTemplatePath,
+		cfg.Proxy.ErrorPage.HTMLTemplatePath, cfg.Proxy.ErrorPage.JSONTemplatePath,
+	}
+	ruleDirs := []string{filepath.Dir(cfg.WAF.CorazaConfigPath)}
+	for _, route := range cfg.Policy.Routes {
+		if dir := filepath.Dir(route.CorazaConfigPath); route.CorazaConfigPath != "" && !slices.Contains(ruleDirs, dir) {
+			ruleDirs = append(ruleDirs, dir)
+		}
+	}
+	for _, dir := range ruleDirs {
+		entries, _ := os.ReadDir(dir)
+		for _, entry := range entries {
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+
+	var sb strings.Builder
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintf(&sb, "%s:-;", path)
+			continue
+		}
+		fmt.Fprintf(&sb, "%s:%d:%d;", path, info.Size(), info.ModTime().UnixNano())
+	}
+	return sb.String()
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/luhtaf/corator/config"
+)
+
+func TestCheckClientAuth(t *testing.T) {
+	partner := config.Config{Policy: config.PolicyConfig{Routes: []config.RouteConfig{
+		{Name: "partner", RequireClientCert: true},
+	}}}
+
+	tests := []struct {
+		name    string
+		running config.TLSConfig
+		wantErr bool
+	}{
+		{"tanpa listener HTTPS", config.TLSConfig{ClientAuth: "none"}, true},
+		{"mTLS nonaktif", config.TLSConfig{ListenAddress: ":8443", ClientAuth: "none"}, true},
+		{"mTLS request", config.TLSConfig{ListenAddress: ":8443", ClientAuth: "request"}, false},
+		{"mTLS require", config.TLSConfig{ListenAddress: ":8443", ClientAuth: "require"}, false},
+	}
+	for _, tt := range tests {
+		if err := checkClientAuth(tt.running, partner); (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, seharusnya error %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	if err := checkClientAuth(config.TLSConfig{ClientAuth: "none"}, config.Config{}); err != nil {
+		t.Errorf("konfigurasi tanpa route mTLS seharusnya diterima: %v", err)
+	}
+}
+
+func TestRestartOnlyChangesReportsTLS(t *testing.T) {
+	old := config.Config{TLS: config.TLSConfig{ListenAddress: ":8443", ClientAuth: "none"}}
+	cfg := old
+	cfg.TLS.ClientAuth = "require"
+	cfg.TLS.ClientCAFile = "/etc/corator/partners-ca.pem"
+
+	if changed := restartOnlyChanges(old, cfg); !slices.Contains(changed, "TLS") {
+		t.Errorf("perubahan TLS tidak dilaporkan: %v", changed)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/luhtaf/corator/config"
+	"github.com/luhtaf/corator/detector"
+	"github.com/luhtaf/corator/handler"
+	"github.com/luhtaf/corator/logger"
+	"github.com/luhtaf/corator/policy"
+	"github.com/luhtaf/corator/tlsserver"
+	"github.com/luhtaf/corator/uploader"
+	"github.com/luhtaf/corator/upstream"
+	"github.com/luhtaf/corator/waf"
+	"gopkg.in/yaml.v3"
+)
+
+// runValidate menjalankan subcommand "validate": memuat konfigurasi, mem-parse rule
+// Coraza, memeriksa koneksi uploader dan logger, lalu mencetak konfigurasi efektif
+// dengan kredensial disamarkan. Mengembalikan exit code, bukan 0 jika ada masalah.
+func runValidate(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path file konfigurasi YAML/TOML/JSON (default: env CORATOR_CONFIG)")
+	timeout := fs.Duration("timeout", validateTimeout, "batas waktu pemeriksaan koneksi uploader dan logger")
+	quiet := fs.Bool("quiet", false, "jangan cetak konfigurasi efektif")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "GAGAL config: %v\n", err)
+		return 1
+	}
+
+	if !*quiet {
+		out, err := yaml.Marshal(cfg.Redacted().Map())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Gagal mencetak konfigurasi efektif: %v\n", err)
+			return 1
+		}
+		fmt.Printf("# Konfigurasi efektif\n%s\n", out)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	problems := validateComponents(ctx, &cfg, os.Stdout)
+	if problems > 0 {
+		fmt.Printf("Ditemukan %d masalah.\n", problems)
+		return 1
+	}
+	fmt.Println("Konfigurasi valid.")
+	return 0
+}
+
+// validateComponents membangun setiap komponen dari konfigurasi dan memeriksa koneksinya,
+// menulis hasil per pemeriksaan ke w, lalu mengembalikan jumlah pemeriksaan yang gagal.
+// Semua pemeriksaan tetap dijalankan meskipun ada yang gagal. Tidak ada file atau
+// direktori yang dibuat: path lokal hanya diperiksa izin tulisnya.
+func validateComponents(ctx context.Context, cfg *config.Config, w io.Writer) int {
+	problems := 0
+	report := func(name string, err error) {
+		if err != nil {
+			problems++
+			fmt.Fprintf(w, "GAGAL %s: %v\n", name, err)
+			return
+		}
+		fmt.Fprintf(w, "OK    %s\n", name)
+	}
+
+	wafs, err := waf.NewWAFs(cfg)
+	report("waf", err)
+
+	_, err = handler.NewBlockPage(cfg.WAF.BlockPage)
+	report("block-page", err)
+
+	_, err = handler.NewErrorPage(cfg.Proxy.ErrorPage)
+	report("error-page", err)
+
+	if cfg.TLS.ListenAddress != "" {
+		_, err = tlsserver.New(cfg.TLS)
+		report("tls", err)
+	}
+
+	uploaders, err := uploader.NewUploaders(cfg)
+	report("uploader", err)
+	for _, up := range uploaders {
+		if up.Name() == "local" {
+			report("uploader:local", checkWritable(cfg.Uploader.Local.Path))
+			continue
+		}
+		report("uploader:"+up.Name(), checkWithTimeout(ctx, up.Check))
+	}
+
+	if cfg.Logger.EnableFile {
+		report("logger:file", checkWritable(cfg.Logger.File.Path))
+	}
+	if cfg.Logger.EnableElastic {
+		elasticLogger, err := logger.NewElasticLogger(cfg.Logger.Elastic)
+		if err == nil {
+			err = checkWithTimeout(ctx, elasticLogger.Check)
+		}
+		report("logger:elastic", err)
+	}
+
+	pools, err := upstream.NewPools(cfg)
+	report("upstream", err)
+
+	if uploaders != nil && wafs != nil && pools != nil {
+		var detectorNames []string
+		for _, d := range detector.NewDetectors(cfg) {
+			detectorNames = append(detectorNames, d.Name())
+		}
+		_, err = policy.New(cfg.Policy, detectorNames, uploaders, wafs, pools)
+		report("policy", err)
+	} else {
+		fmt.Fprintln(w, "LEWAT policy: membutuhkan waf, uploader dan upstream yang valid")
+	}
+
+	return problems
+}
+
+// checkWritable memastikan path bisa ditulisi, atau bisa dibuat di direktori terdekat
+// yang sudah ada, tanpa membuat file atau direktori apa pun.
+func checkWritable(path string) error {
+	if path == "" {
+		return fmt.Errorf("path tidak boleh kosong")
+	}
+
+	existing := filepath.Clean(path)
+	for {
+		info, err := os.Stat(existing)
+		if err == nil {
+			if existing != filepath.Clean(path) && !info.IsDir() {
+				return fmt.Errorf("%s bukan direktori", existing)
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return fmt.Errorf("tidak ada direktori induk %s yang sudah ada", path)
+		}
+		existing = parent
+	}
+
+	if err := canWrite(existing); err != nil {
+		return fmt.Errorf("%s tidak bisa ditulisi: %w", existing, err)
+	}
+	return nil
+}
+
+// checkWithTimeout menjalankan satu pemeriksaan koneksi dan mengukur durasinya
+// agar pemeriksaan yang lambat terlihat di output.
+func checkWithTimeout(ctx context.Context, check func(context.Context) error) error {
+	started := time.Now()
+	err := check(ctx)
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("timeout setelah %s: %w", time.Since(started).Round(time.Millisecond), err)
+	}
+	return err
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/luhtaf/corator/config"
+)
+
+func TestValidateComponentsHasNoSideEffects(t *testing.T) {
+	dir := t.TempDir()
+	rules := filepath.Join(dir, "coraza.conf")
+	if err := os.WriteFile(rules, []byte("SecRuleEngine On\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	evidenceDir := filepath.Join(dir, "evidence", "nested")
+	logFile := filepath.Join(dir, "logs", "corator.log")
+	if err := os.Mkdir(filepath.Dir(logFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Config{
+		Server:   config.ServerConfig{BackendURL: "http://127.0.0.1:8081"},
+		WAF:      config.WAFConfig{CorazaConfigPath: rules},
+		Uploader: config.UploaderConfig{Type: "local", Local: config.LocalConfig{Path: evidenceDir}},
+		Logger:   config.LoggerConfig{EnableFile: true, File: config.FileLoggerConfig{Path: logFile}},
+	}
+
+	var out strings.Builder
+	if problems := validateComponents(context.Background(), &cfg, &out); problems != 0 {
+		t.Fatalf("ditemukan %d masalah:\n%s", problems, out.String())
+	}
+	for _, line := range []string{"OK    uploader:local", "OK    logger:file", "OK    policy"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("output tidak berisi %q:\n%s", line, out.String())
+		}
+	}
+
+	for _, path := range []string{filepath.Join(dir, "evidence"), logFile} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("validate membuat %s", path)
+		}
+	}
+}
+
+func TestValidateComponentsReportsSkippedPolicy(t *testing.T) {
+	cfg := config.Config{
+		WAF:      config.WAFConfig{CorazaConfigPath: filepath.Join(t.TempDir(), "tidak-ada.conf")},
+		Uploader: config.UploaderConfig{Type: "local", Local: config.LocalConfig{Path: t.TempDir()}},
+	}
+
+	var out strings.Builder
+	validateComponents(context.Background(), &cfg, &out)
+	if !strings.Contains(out.String(), "LEWAT policy") {
+		t.Errorf("policy yang tidak diperiksa tidak dilaporkan:\n%s", out.String())
+	}
+}
+
+func TestCheckWritable(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := checkWritable(filepath.Join(dir, "a", "b")); err != nil {
+		t.Errorf("path baru di direktori yang bisa ditulisi: %v", err)
+	}
+	if err := checkWritable(filepath.Join(file, "sub")); err == nil {
+		t.Errorf("path di bawah file biasa seharusnya ditolak")
+	}
+	if err := checkWritable(""); err == nil {
+		t.Errorf("path kosong seharusnya ditolak")
+	}
+}
@@ -0,0 +1,30 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// canWrite memeriksa izin tulis path yang sudah ada. Tanpa syscall.Access (misal di
+// Windows), direktori diperiksa dengan membuat lalu menghapus file sementara, dan file
+// dengan membukanya untuk ditulis tanpa memotong isinya.
+func canWrite(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		file, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return err
+		}
+		return file.Close()
+	}
+
+	probe, err := os.CreateTemp(path, ".corator-validate-*")
+	if err != nil {
+		return err
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
@@ -0,0 +1,13 @@
+//go:build unix
+
+package main
+
+import "syscall"
+
+// accessWrite adalah mode pemeriksaan izin tulis untuk syscall.Access (W_OK).
+const accessWrite = 0x2
+
+// canWrite memeriksa izin tulis path yang sudah ada tanpa mengubah apa pun.
+func canWrite(path string) error {
+	return syscall.Access(path, accessWrite)
+}
@@ -1,33 +1,159 @@
 package config
 
 import (
+	"fmt"
+	"log"
+	"os"
+	"reflect"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 // Config menampung semua konfigurasi untuk aplikasi.
-// Nilai-nilai ini dibaca dari environment variables.
+// Nilai-nilai ini dibaca dari file konfigurasi dan environment variables.
 type Config struct {
 	Server    ServerConfig
+	TLS       TLSConfig
+	Proxy     ProxyConfig
+	Admin     AdminConfig
+	Buffer    BufferConfig
 	WAF       WAFConfig
 	Detectors DetectorConfig
+	Archive   ArchiveConfig
 	Uploader  UploaderConfig
 	Logger    LoggerConfig
+	Spool     SpoolConfig
+	Worker    WorkerConfig
+	Policy    PolicyConfig
+	Reload    ReloadConfig
+	Upstreams []UpstreamConfig
 }
 
 type ServerConfig struct {
+	ListenAddress string        `mapstructure:"LISTEN_ADDRESS"`
+	BackendURL    string        `mapstructure:"BACKEND_URL"`
+	Upstream      string        `mapstructure:"UPSTREAM"`      // Nama upstream pool default, menggantikan BackendURL jika diisi
+	DrainTimeout  time.Duration `mapstructure:"DRAIN_TIMEOUT"` // Batas waktu shutdown untuk request dan file bukti yang sedang diproses
+}
+
+// TLSConfig mengatur listener HTTPS yang menerima traffic yang sama dengan SERVER_LISTEN_ADDRESS.
+// Listener dinonaktifkan jika ListenAddress kosong. File sertifikat dimuat ulang saat berubah.
+type TLSConfig struct {
+	ListenAddress string `mapstructure:"LISTEN_ADDRESS"`
+	CertFile      string `mapstructure:"CERT_FILE"` // Sertifikat default (PEM, boleh berisi rantai intermediate)
+	KeyFile       string `mapstructure:"KEY_FILE"`
+
+	// Certificates adalah sertifikat tambahan yang dipilih berdasarkan SNI dari nama di
+	// sertifikatnya. Seperti route, daftar ini hanya bisa ditulis di file.
+	Certificates []CertificateConfig `mapstructure:"CERTIFICATES"`
+
+	MinVersion   string   `mapstructure:"MIN_VERSION"`   // "1.0", "1.1", "1.2" atau "1.3"
+	MaxVersion   string   `mapstructure:"MAX_VERSION"`   // Kosong berarti versi tertinggi yang didukung
+	CipherSuites []string `mapstructure:"CIPHER_SUITES"` // Nama cipher suite TLS 1.2, kosong berarti default Go
+
+	// ReloadInterval adalah jeda pemeriksaan perubahan file sertifikat, 0 berarti hanya saat SIGHUP.
+	ReloadInterval time.Duration `mapstructure:"RELOAD_INTERVAL"`
+
+	// ClientAuth adalah "none" (default), "request" (sertifikat client diverifikasi jika
+	// dikirim, route bisa mewajibkannya) atau "require" (semua client wajib mengirim).
+	ClientAuth   string `mapstructure:"CLIENT_AUTH"`
+	ClientCAFile string `mapstructure:"CLIENT_CA_FILE"` // CA bundle PEM untuk memverifikasi sertifikat client
+
+	ACME ACMEConfig `mapstructure:"ACME"`
+}
+
+// CertificateConfig adalah satu pasangan file sertifikat dan private key PEM.
+type CertificateConfig struct {
+	CertFile string `mapstructure:"CERT_FILE"`
+	KeyFile  string `mapstructure:"KEY_FILE"`
+}
+
+// ACMEConfig mengatur penerbitan sertifikat otomatis lewat ACME (misal Let's Encrypt).
+// Challenge TLS-ALPN-01 dijawab di listener HTTPS dan HTTP-01 di SERVER_LISTEN_ADDRESS.
+type ACMEConfig struct {
+	Enable       bool     `mapstructure:"ENABLE"`
+	Domains      []string `mapstructure:"DOMAINS"` // Hanya domain ini yang boleh dimintakan sertifikat
+	Email        string   `mapstructure:"EMAIL"`
+	DirectoryURL string   `mapstructure:"DIRECTORY_URL"` // Kosong berarti Let's Encrypt production
+	CacheDir     string   `mapstructure:"CACHE_DIR"`     // Penyimpanan akun dan sertifikat yang sudah terbit
+	CAFile       string   `mapstructure:"CA_FILE"`       // CA tambahan untuk server ACME, misal root pebble
+}
+
+// ProxyConfig mengatur koneksi reverse proxy ke backend: connection pooling, timeout,
+// HTTP/2, TLS ke backend, header forwarding dan halaman error 502/504.
+type ProxyConfig struct {
+	DialTimeout           time.Duration `mapstructure:"DIAL_TIMEOUT"`
+	KeepAlive             time.Duration `mapstructure:"KEEP_ALIVE"`
+	TLSHandshakeTimeout   time.Duration `mapstructure:"TLS_HANDSHAKE_TIMEOUT"`
+	ResponseHeaderTimeout time.Duration `mapstructure:"RESPONSE_HEADER_TIMEOUT"` // 0 berarti tanpa batas
+	IdleConnTimeout       time.Duration `mapstructure:"IDLE_CONN_TIMEOUT"`
+	MaxIdleConns          int           `mapstructure:"MAX_IDLE_CONNS"`
+	MaxIdleConnsPerHost   int           `mapstructure:"MAX_IDLE_CONNS_PER_HOST"`
+	MaxConnsPerHost       int           `mapstructure:"MAX_CONNS_PER_HOST"` // 0 berarti tanpa batas
+
+	// HTTP2 adalah "auto" (HTTP/2 lewat ALPN untuk backend https), "off" (hanya HTTP/1.1)
+	// atau "h2c" (hanya HTTP/2, tanpa TLS untuk backend http).
+	HTTP2 string `mapstructure:"HTTP2"`
+
+	TLSCAFile             string `mapstructure:"TLS_CA_FILE"` // CA tambahan untuk memverifikasi backend https
+	TLSInsecureSkipVerify bool   `mapstructure:"TLS_INSECURE_SKIP_VERIFY"`
+
+	// TrustedProxies adalah IP atau CIDR proxy di depan Corator yang header
+	// X-Forwarded-* dan Forwarded-nya dipercaya dan diteruskan.
+	TrustedProxies []string `mapstructure:"TRUSTED_PROXIES"`
+
+	ErrorPage BlockPageConfig `mapstructure:"ERROR_PAGE"` // Template halaman 502/504
+}
+
+// AdminConfig mengatur listener admin untuk endpoint internal seperti /metrics.
+// Listener dinonaktifkan jika ListenAddress kosong.
+type AdminConfig struct {
 	ListenAddress string `mapstructure:"LISTEN_ADDRESS"`
-	BackendURL    string `mapstructure:"BACKEND_URL"`
+}
+
+// BufferConfig mengatur buffer body request dan file hasil deteksi.
+// Data di atas MemoryLimit byte dipindahkan ke file sementara di TempDir.
+type BufferConfig struct {
+	MemoryLimit int64  `mapstructure:"MEMORY_LIMIT"`
+	TempDir     string `mapstructure:"TEMP_DIR"`
+
+	// MaxBodySize adalah ukuran maksimum body request yang disalin ke buffer. Request
+	// yang lebih besar ditolak dengan 413 agar satu request tidak memenuhi disk.
+	MaxBodySize int64 `mapstructure:"MAX_BODY_SIZE"`
 }
 
 type WAFConfig struct {
-	CorazaConfigPath string `mapstructure:"CORAZA_CONFIG_PATH"`
+	CorazaConfigPath string          `mapstructure:"CORAZA_CONFIG_PATH"`
+	BlockPage        BlockPageConfig `mapstructure:"BLOCK_PAGE"`
+	ExportDetections bool            `mapstructure:"EXPORT_DETECTIONS"` // Salin hasil deteksi ke variabel TX Coraza
+}
+
+// BlockPageConfig menentukan template halaman blokir. Jika kosong, template bawaan digunakan.
+type BlockPageConfig struct {
+	HTMLTemplatePath string `mapstructure:"HTML_TEMPLATE"`
+	JSONTemplatePath string `mapstructure:"JSON_TEMPLATE"`
 }
 
 type DetectorConfig struct {
-	EnableFile   bool `mapstructure:"ENABLE_FILE_DETECTOR"`
-	EnableBase64 bool `mapstructure:"ENABLE_BASE64_DETECTOR"`
+	EnableFile   bool `mapstructure:"ENABLE_FILE"`
+	EnableBase64 bool `mapstructure:"ENABLE_BASE64"`
+	EnableJSON   bool `mapstructure:"ENABLE_JSON"`
+	EnableRaw    bool `mapstructure:"ENABLE_RAW"`
+
+	// RawAllowedTypes adalah tipe MIME body mentah yang ditangkap RawDetector,
+	// dicocokkan dengan Content-Type maupun hasil sniffing. Mendukung wildcard "image/*".
+	RawAllowedTypes []string `mapstructure:"RAW_ALLOWED_TYPES"`
+}
+
+// ArchiveConfig mengatur ekstraksi rekursif archive (ZIP, TAR, GZIP, BZIP2) dari file
+// hasil deteksi. Batasnya berlaku untuk seluruh pohon archive untuk mencegah zip bomb.
+type ArchiveConfig struct {
+	Enable       bool  `mapstructure:"ENABLE"`
+	MaxDepth     int   `mapstructure:"MAX_DEPTH"`      // Kedalaman nesting maksimal yang dibongkar
+	MaxMembers   int   `mapstructure:"MAX_MEMBERS"`    // Jumlah member maksimal per file bukti
+	MaxTotalSize int64 `mapstructure:"MAX_TOTAL_SIZE"` // Total byte hasil dekompresi maksimal per file bukti
 }
 
 type UploaderConfig struct {
@@ -64,21 +190,227 @@ type ElasticConfig struct {
 	Index string   `mapstructure:"INDEX"`
 }
 
-// LoadConfig membaca konfigurasi dari environment variables.
-func LoadConfig() (cfg Config, err error) {
+// SpoolConfig mengatur antrean di disk untuk upload dan log event yang gagal dikirim.
+type SpoolConfig struct {
+	Path          string        `mapstructure:"PATH"`
+	RetryInterval time.Duration `mapstructure:"RETRY_INTERVAL"`
+	MinBackoff    time.Duration `mapstructure:"MIN_BACKOFF"`
+	MaxBackoff    time.Duration `mapstructure:"MAX_BACKOFF"`
+}
+
+// WorkerConfig mengatur worker pool untuk upload dan logging file bukti.
+type WorkerConfig struct {
+	Count          int    `mapstructure:"COUNT"`
+	QueueSize      int    `mapstructure:"QUEUE_SIZE"`
+	OverflowPolicy string `mapstructure:"OVERFLOW_POLICY"` // "block", "drop-oldest" atau "spill"
+}
+
+// PolicyConfig berisi route intersepsi per host, path dan method. Setiap route bisa
+// meneruskan request ke backend dan rule set Coraza sendiri. Route dibaca dari
+// file YAML/JSON di File karena tidak praktis ditulis sebagai environment variable.
+type PolicyConfig struct {
+	File   string        `mapstructure:"FILE"`
+	Routes []RouteConfig `mapstructure:"ROUTES"`
+}
+
+// RouteConfig adalah satu route policy. Host dan path berupa glob ("*" cocok dengan
+// karakter apa pun termasuk "/") atau regex jika diawali "~". Kriteria kosong cocok
+// dengan semua request.
+type RouteConfig struct {
+	Name        string   `mapstructure:"NAME"`
+	Hosts       []string `mapstructure:"HOSTS"`
+	Paths       []string `mapstructure:"PATHS"`
+	Methods     []string `mapstructure:"METHODS"`
+	Action      string   `mapstructure:"ACTION"`        // "record" (default), "block" atau "ignore"
+	Detectors   []string `mapstructure:"DETECTORS"`     // Nama detektor yang dijalankan, kosong berarti semua
+	MaxFileSize int64    `mapstructure:"MAX_FILE_SIZE"` // File yang lebih besar tidak ditangkap, 0 berarti tanpa batas
+	AllowMime   []string `mapstructure:"ALLOW_MIME"`
+	DenyMime    []string `mapstructure:"DENY_MIME"`
+	Uploader    string   `mapstructure:"UPLOADER"` // "local" atau "s3", kosong berarti uploader default
+
+	// Backend adalah URL upstream untuk route ini dan Upstream adalah nama upstream pool;
+	// keduanya kosong berarti backend default (SERVER_UPSTREAM atau SERVER_BACKEND_URL).
+	Backend  string `mapstructure:"BACKEND"`
+	Upstream string `mapstructure:"UPSTREAM"`
+	// CorazaConfigPath adalah rule set Coraza khusus route ini, kosong berarti WAF_CORAZA_CONFIG_PATH.
+	CorazaConfigPath string `mapstructure:"CORAZA_CONFIG_PATH"`
+	// RequireClientCert menolak request tanpa sertifikat client yang terverifikasi (mTLS).
+	RequireClientCert bool `mapstructure:"REQUIRE_CLIENT_CERT"`
+}
+
+// ReloadConfig mengatur reload konfigurasi tanpa restart. Reload selalu bisa dipicu
+// dengan SIGHUP; WatchInterval > 0 juga memeriksa perubahan file secara berkala.
+type ReloadConfig struct {
+	WatchInterval time.Duration `mapstructure:"WATCH_INTERVAL"`
+}
+
+// UpstreamConfig adalah satu upstream pool: sekumpulan replika backend yang dipilih
+// dengan strategi load balancing, dipantau dengan health check aktif dan dikeluarkan
+// sementara setelah error berturut-turut. Seperti route, pool hanya bisa ditulis di file.
+type UpstreamConfig struct {
+	Name     string   `mapstructure:"NAME"`
+	Targets  []string `mapstructure:"TARGETS"`  // URL setiap replika
+	Strategy string   `mapstructure:"STRATEGY"` // "round-robin" (default), "least-conn" atau "consistent-hash"
+	HashKey  string   `mapstructure:"HASH_KEY"` // Untuk consistent-hash: "remote-addr" (default), "header:<nama>" atau "cookie:<nama>"
+
+	// Retries adalah jumlah percobaan ulang ke replika lain untuk request idempotent
+	// yang gagal terhubung atau mendapat 502/503/504.
+	Retries int `mapstructure:"RETRIES"`
+
+	// MaxFails error berturut-turut mengeluarkan replika selama FailTimeout. 0 menonaktifkan.
+	MaxFails    int           `mapstructure:"MAX_FAILS"`
+	FailTimeout time.Duration `mapstructure:"FAIL_TIMEOUT"`
+
+	HealthCheck HealthCheckConfig `mapstructure:"HEALTH_CHECK"`
+}
+
+// HealthCheckConfig mengatur health check HTTP aktif untuk replika upstream.
+// Health check dinonaktifkan jika Path kosong.
+type HealthCheckConfig struct {
+	Path               string        `mapstructure:"PATH"`
+	Interval           time.Duration `mapstructure:"INTERVAL"`
+	Timeout            time.Duration `mapstructure:"TIMEOUT"`
+	HealthyThreshold   int           `mapstructure:"HEALTHY_THRESHOLD"`   // Sukses berturut-turut untuk kembali sehat
+	UnhealthyThreshold int           `mapstructure:"UNHEALTHY_THRESHOLD"` // Gagal berturut-turut untuk dianggap mati
+}
+
+// LoadConfig membaca konfigurasi dari file (opsional) dan environment variables.
+// configPath kosong berarti memakai env CORATOR_CONFIG; jika keduanya kosong, hanya
+// environment variables yang dipakai. Environment variables selalu menimpa nilai di file.
+// Key yang tidak dikenal di file dan nilai wajib yang kosong dilaporkan sebagai error.
+func LoadConfig(configPath string) (cfg Config, err error) {
+	v := viper.New()
+
 	// Menetapkan nilai default
-	viper.SetDefault("SERVER_LISTEN_ADDRESS", ":8080")
-	viper.SetDefault("SERVER_BACKEND_URL", "http://localhost:3000")
-	viper.SetDefault("UPLOADER_TYPE", "local")
-	viper.SetDefault("UPLOADER_LOCAL_PATH", "/tmp/uploads")
-	viper.SetDefault("LOGGER_FILE_PATH", "/tmp/interceptor.log")
-	viper.SetDefault("LOGGER_ELASTIC_INDEX", "coraza-interceptor")
+	v.SetDefault("SERVER.LISTEN_ADDRESS", ":8080")
+	v.SetDefault("SERVER.BACKEND_URL", "http://localhost:3000")
+	v.SetDefault("SERVER.DRAIN_TIMEOUT", "25s")
+	v.SetDefault("TLS.MIN_VERSION", "1.2")
+	v.SetDefault("TLS.RELOAD_INTERVAL", "1m")
+	v.SetDefault("TLS.CLIENT_AUTH", "none")
+	v.SetDefault("TLS.ACME.CACHE_DIR", "/tmp/corator_acme")
+	v.SetDefault("PROXY.DIAL_TIMEOUT", "5s")
+	v.SetDefault("PROXY.KEEP_ALIVE", "30s")
+	v.SetDefault("PROXY.TLS_HANDSHAKE_TIMEOUT", "10s")
+	v.SetDefault("PROXY.RESPONSE_HEADER_TIMEOUT", "60s")
+	v.SetDefault("PROXY.IDLE_CONN_TIMEOUT", "90s")
+	v.SetDefault("PROXY.MAX_IDLE_CONNS", 100)
+	v.SetDefault("PROXY.MAX_IDLE_CONNS_PER_HOST", 32)
+	v.SetDefault("PROXY.HTTP2", "auto")
+	v.SetDefault("ADMIN.LISTEN_ADDRESS", ":9090")
+	v.SetDefault("BUFFER.MEMORY_LIMIT", 4<<20)
+	v.SetDefault("BUFFER.MAX_BODY_SIZE", 2<<30)
+	v.SetDefault("DETECTORS.RAW_ALLOWED_TYPES", []string{
+		"application/octet-stream", "application/offset+octet-stream", "application/pdf",
+		"application/zip", "application/x-gzip", "image/*", "audio/*", "video/*",
+	})
+	v.SetDefault("ARCHIVE.MAX_DEPTH", 3)
+	v.SetDefault("ARCHIVE.MAX_MEMBERS", 1000)
+	v.SetDefault("ARCHIVE.MAX_TOTAL_SIZE", 256<<20)
+	v.SetDefault("UPLOADER.TYPE", "local")
+	v.SetDefault("UPLOADER.LOCAL.PATH", "/tmp/uploads")
+	v.SetDefault("LOGGER.FILE.PATH", "/tmp/interceptor.log")
+	v.SetDefault("LOGGER.ELASTIC.INDEX", "coraza-interceptor")
+	v.SetDefault("SPOOL.PATH", "/tmp/corator_spool")
+	v.SetDefault("SPOOL.RETRY_INTERVAL", "10s")
+	v.SetDefault("SPOOL.MIN_BACKOFF", "5s")
+	v.SetDefault("SPOOL.MAX_BACKOFF", "10m")
+	v.SetDefault("WORKER.COUNT", 8)
+	v.SetDefault("WORKER.QUEUE_SIZE", 256)
+	v.SetDefault("WORKER.OVERFLOW_POLICY", "block")
+
+	// File konfigurasi YAML, TOML atau JSON, formatnya ditentukan dari ekstensi
+	if configPath == "" {
+		configPath = os.Getenv("CORATOR_CONFIG")
+	}
+	if configPath != "" {
+		v.SetConfigFile(configPath)
+		if err = v.ReadInConfig(); err != nil {
+			return cfg, fmt.Errorf("gagal membaca file konfigurasi %s: %w", configPath, err)
+		}
+	}
 
 	// Mengaktifkan pembacaan dari environment variables
-	viper.AutomaticEnv()
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+
+	// Env yang di-set kosong tetap menimpa nilai lain, misalnya ADMIN_LISTEN_ADDRESS=
+	// untuk menonaktifkan listener admin. Hapus env-nya jika nilai dari file yang ingin dipakai.
+	v.AllowEmptyEnv(true)
+
+	// AutomaticEnv hanya berlaku untuk key yang sudah dikenal viper, jadi semua
+	// key dari struct didaftarkan agar env seperti WAF_CORAZA_CONFIG_PATH terbaca.
+	bindEnvs(v, reflect.TypeOf(cfg), "")
+	for key, legacy := range legacyEnvs {
+		if _, ok := os.LookupEnv(legacy); ok {
+			log.Printf("PERINGATAN: Env %s sudah usang, gunakan %s", legacy, envName(key))
+		}
+	}
+
+	// Unmarshal konfigurasi ke struct, key yang tidak dikenal (misal salah ketik) ditolak
+	if err = v.UnmarshalExact(&cfg); err != nil {
+		return cfg, fmt.Errorf("konfigurasi tidak valid: %w", err)
+	}
+
+	if cfg.Policy.File != "" {
+		if cfg.Policy.Routes, err = loadRoutes(cfg.Policy.File); err != nil {
+			return cfg, err
+		}
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// loadRoutes membaca daftar route policy dari file YAML atau JSON dengan key "routes".
+func loadRoutes(path string) ([]RouteConfig, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("gagal membaca file policy %s: %w", path, err)
+	}
+
+	var policy PolicyConfig
+	if err := v.UnmarshalExact(&policy); err != nil {
+		return nil, fmt.Errorf("file policy %s tidak valid: %w", path, err)
+	}
+	return policy.Routes, nil
+}
+
+// bindEnvs mendaftarkan setiap field struct konfigurasi ke viper secara rekursif,
+// dengan key bertingkat seperti "UPLOADER.S3.BUCKET" (env: UPLOADER_S3_BUCKET).
+func bindEnvs(v *viper.Viper, t reflect.Type, prefix string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("mapstructure")
+		if name == "" {
+			name = strings.ToUpper(field.Name)
+		}
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			bindEnvs(v, field.Type, key)
+			continue
+		}
+		if legacy, ok := legacyEnvs[key]; ok {
+			v.BindEnv(key, envName(key), legacy)
+			continue
+		}
+		v.BindEnv(key)
+	}
+}
+
+// legacyEnvs memetakan key konfigurasi ke nama env lama yang masih diterima agar
+// deployment lama tidak rusak. Nama env baru selalu diutamakan jika keduanya di-set.
+var legacyEnvs = map[string]string{
+	"DETECTORS.ENABLE_FILE":   "DETECTORS_ENABLE_FILE_DETECTOR",
+	"DETECTORS.ENABLE_BASE64": "DETECTORS_ENABLE_BASE64_DETECTOR",
+}
 
-	// Unmarshal konfigurasi ke struct
-	err = viper.Unmarshal(&cfg)
-	return
+// envName mengembalikan nama env untuk key bertingkat, misal "DETECTORS.ENABLE_FILE"
+// menjadi DETECTORS_ENABLE_FILE.
+func envName(key string) string {
+	return strings.ReplaceAll(key, ".", "_")
 }
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigReadsNestedEnv(t *testing.T) {
+	t.Setenv("CORATOR_CONFIG", "")
+	t.Setenv("WAF_CORAZA_CONFIG_PATH", "coraza.conf")
+	t.Setenv("UPLOADER_S3_BUCKET", "bukti")
+
+	cfg, err := LoadConfig("")
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.WAF.CorazaConfigPath != "coraza.conf" || cfg.Uploader.S3.Bucket != "bukti" {
+		t.Errorf("env bertingkat tidak terbaca: WAF %q, bucket S3 %q", cfg.WAF.CorazaConfigPath, cfg.Uploader.S3.Bucket)
+	}
+	if cfg.Server.ListenAddress != ":8080" {
+		t.Errorf("nilai default SERVER_LISTEN_ADDRESS = %q, seharusnya :8080", cfg.Server.ListenAddress)
+	}
+}
+
+func TestLoadConfigAcceptsLegacyDetectorEnv(t *testing.T) {
+	t.Setenv("CORATOR_CONFIG", "")
+	t.Setenv("WAF_CORAZA_CONFIG_PATH", "coraza.conf")
+	t.Setenv("DETECTORS_ENABLE_FILE_DETECTOR", "true")
+	t.Setenv("DETECTORS_ENABLE_BASE64_DETECTOR", "true")
+	t.Setenv("DETECTORS_ENABLE_BASE64", "false") // Nama baru diutamakan
+
+	cfg, err := LoadConfig("")
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if !cfg.Detectors.EnableFile {
+		t.Error("DETECTORS_ENABLE_FILE_DETECTOR tidak lagi mengaktifkan detektor file")
+	}
+	if cfg.Detectors.EnableBase64 {
+		t.Error("DETECTORS_ENABLE_BASE64 seharusnya menimpa nama env lama")
+	}
+}
+
+func TestValidateRejectsDropOldestWithoutQueue(t *testing.T) {
+	t.Setenv("CORATOR_CONFIG", "")
+	t.Setenv("WAF_CORAZA_CONFIG_PATH", "coraza.conf")
+	t.Setenv("WORKER_OVERFLOW_POLICY", "drop-oldest")
+	t.Setenv("WORKER_QUEUE_SIZE", "0")
+
+	if _, err := LoadConfig(""); err == nil || !strings.Contains(err.Error(), "WORKER_QUEUE_SIZE") {
+		t.Errorf("LoadConfig seharusnya menolak drop-oldest tanpa antrean, error: %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// redactedValue menggantikan nilai rahasia pada konfigurasi yang dicetak.
+const redactedValue = "REDACTED"
+
+// Redacted mengembalikan salinan konfigurasi dengan kredensial disamarkan,
+// termasuk password yang tertanam di URL, sehingga aman untuk dicetak atau dicatat.
+func (c Config) Redacted() Config {
+	c.Server.BackendURL = redactURL(c.Server.BackendURL)
+	c.Uploader.S3.Endpoint = redactURL(c.Uploader.S3.Endpoint)
+	c.Uploader.S3.AccessKey = redact(c.Uploader.S3.AccessKey)
+	c.Uploader.S3.SecretKey = redact(c.Uploader.S3.SecretKey)
+
+	urls := make([]string, len(c.Logger.Elastic.URLs))
+	for i, u := range c.Logger.Elastic.URLs {
+		urls[i] = redactURL(u)
+	}
+	c.Logger.Elastic.URLs = urls
+	c.TLS.ACME.DirectoryURL = redactURL(c.TLS.ACME.DirectoryURL)
+
+	// Slice disalin agar konfigurasi asli tidak ikut tersamarkan
+	upstreams := make([]UpstreamConfig, len(c.Upstreams))
+	for i, up := range c.Upstreams {
+		targets := make([]string, len(up.Targets))
+		for j, target := range up.Targets {
+			targets[j] = redactURL(target)
+		}
+		up.Targets = targets
+		upstreams[i] = up
+	}
+	c.Upstreams = upstreams
+
+	routes := make([]RouteConfig, len(c.Policy.Routes))
+	for i, route := range c.Policy.Routes {
+		route.Backend = redactURL(route.Backend)
+		routes[i] = route
+	}
+	c.Policy.Routes = routes
+	return c
+}
+
+// Map mengubah konfigurasi menjadi map bertingkat dengan key yang sama seperti di
+// file konfigurasi (misal server.listen_address), untuk dicetak sebagai YAML atau JSON.
+func (c Config) Map() map[string]any {
+	return structToMap(reflect.ValueOf(c))
+}
+
+// redact menyamarkan nilai yang tidak kosong.
+func redact(value string) string {
+	if value == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+// redactURL menyamarkan password pada userinfo URL.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redactedValue)
+	}
+	return u.String()
+}
+
+// structToMap mengubah struct konfigurasi menjadi map dengan key dari tag mapstructure
+// dalam huruf kecil. Durasi ditulis dalam format seperti "10s".
+func structToMap(v reflect.Value) map[string]any {
+	t := v.Type()
+	m := make(map[string]any, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("mapstructure")
+		if name == "" {
+			name = field.Name
+		}
+		m[strings.ToLower(name)] = valueToAny(v.Field(i))
+	}
+	return m
+}
+
+func valueToAny(v reflect.Value) any {
+	if d, ok := v.Interface().(time.Duration); ok {
+		return d.String()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		return structToMap(v)
+	case reflect.Slice:
+		items := make([]any, v.Len())
+		for i := range items {
+			items[i] = valueToAny(v.Index(i))
+		}
+		return items
+	default:
+		return v.Interface()
+	}
+}
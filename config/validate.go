@@ -0,0 +1,216 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Validate memeriksa nilai wajib dan kombinasi konfigurasi yang tidak valid.
+// Semua masalah dikumpulkan sekaligus agar bisa diperbaiki dalam satu kali jalan.
+// Nama pada pesan error memakai nama environment variable-nya.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.ListenAddress != "" || c.TLS.ListenAddress != "", "SERVER_LISTEN_ADDRESS atau TLS_LISTEN_ADDRESS wajib diisi")
+	check(validURL(c.Server.BackendURL), "SERVER_BACKEND_URL harus berupa URL http(s) yang valid, bukan %q", c.Server.BackendURL)
+	check(c.Server.DrainTimeout > 0, "SERVER_DRAIN_TIMEOUT harus lebih dari 0")
+
+	errs = append(errs, validateTLS(c.TLS)...)
+
+	check(c.Proxy.DialTimeout > 0, "PROXY_DIAL_TIMEOUT harus lebih dari 0")
+	check(c.Proxy.ResponseHeaderTimeout >= 0 && c.Proxy.TLSHandshakeTimeout >= 0 && c.Proxy.IdleConnTimeout >= 0,
+		"timeout PROXY_* tidak boleh negatif")
+	check(c.Proxy.MaxIdleConns >= 0 && c.Proxy.MaxIdleConnsPerHost >= 0 && c.Proxy.MaxConnsPerHost >= 0,
+		"batas koneksi PROXY_* tidak boleh negatif")
+	check(slices.Contains([]string{"auto", "off", "h2c"}, c.Proxy.HTTP2),
+		"PROXY_HTTP2 harus auto, off atau h2c, bukan %q", c.Proxy.HTTP2)
+	for _, entry := range c.Proxy.TrustedProxies {
+		check(validIPOrCIDR(entry), "PROXY_TRUSTED_PROXIES berisi IP atau CIDR yang tidak valid: %q", entry)
+	}
+
+	check(c.WAF.CorazaConfigPath != "", "WAF_CORAZA_CONFIG_PATH wajib diisi")
+	check(c.Buffer.MemoryLimit > 0, "BUFFER_MEMORY_LIMIT harus lebih dari 0")
+	check(c.Buffer.MaxBodySize > 0, "BUFFER_MAX_BODY_SIZE harus lebih dari 0")
+
+	errs = append(errs, validateUploader(c.Uploader, c.Uploader.Type)...)
+
+	if c.Logger.EnableFile {
+		check(c.Logger.File.Path != "", "LOGGER_FILE_PATH wajib diisi jika LOGGER_ENABLE_FILE aktif")
+	}
+	if c.Logger.EnableElastic {
+		check(len(c.Logger.Elastic.URLs) > 0, "LOGGER_ELASTIC_URLS wajib diisi jika LOGGER_ENABLE_ELASTIC aktif")
+		for _, u := range c.Logger.Elastic.URLs {
+			check(validURL(u), "LOGGER_ELASTIC_URLS berisi URL yang tidak valid: %q", u)
+		}
+		check(c.Logger.Elastic.Index != "", "LOGGER_ELASTIC_INDEX wajib diisi jika LOGGER_ENABLE_ELASTIC aktif")
+	}
+
+	check(c.Spool.Path != "", "SPOOL_PATH wajib diisi")
+	check(c.Spool.RetryInterval > 0, "SPOOL_RETRY_INTERVAL harus lebih dari 0")
+	check(c.Spool.MinBackoff > 0 && c.Spool.MinBackoff <= c.Spool.MaxBackoff,
+		"SPOOL_MIN_BACKOFF harus lebih dari 0 dan tidak melebihi SPOOL_MAX_BACKOFF")
+
+	check(c.Worker.Count > 0, "WORKER_COUNT harus lebih dari 0")
+	check(c.Worker.QueueSize >= 0, "WORKER_QUEUE_SIZE tidak boleh negatif")
+	check(slices.Contains([]string{"block", "drop-oldest", "spill"}, c.Worker.OverflowPolicy),
+		"WORKER_OVERFLOW_POLICY harus block, drop-oldest atau spill, bukan %q", c.Worker.OverflowPolicy)
+	check(c.Worker.OverflowPolicy != "drop-oldest" || c.Worker.QueueSize > 0,
+		"WORKER_QUEUE_SIZE harus lebih dari 0 untuk WORKER_OVERFLOW_POLICY drop-oldest")
+
+	if c.Archive.Enable {
+		check(c.Archive.MaxDepth > 0, "ARCHIVE_MAX_DEPTH harus lebih dari 0")
+		check(c.Archive.MaxMembers > 0, "ARCHIVE_MAX_MEMBERS harus lebih dari 0")
+		check(c.Archive.MaxTotalSize > 0, "ARCHIVE_MAX_TOTAL_SIZE harus lebih dari 0")
+	}
+
+	check(c.Reload.WatchInterval >= 0, "RELOAD_WATCH_INTERVAL tidak boleh negatif")
+
+	var upstreamNames []string
+	for i, up := range c.Upstreams {
+		name := up.Name
+		check(name != "", "upstream #%d: name wajib diisi", i+1)
+		check(!slices.Contains(upstreamNames, name), "upstream %s: nama sudah dipakai upstream lain", name)
+		upstreamNames = append(upstreamNames, name)
+
+		check(len(up.Targets) > 0, "upstream %s: targets wajib diisi", name)
+		for _, target := range up.Targets {
+			check(validURL(target), "upstream %s: target harus berupa URL http(s) yang valid, bukan %q", name, target)
+		}
+		check(slices.Contains([]string{"", "round-robin", "least-conn", "consistent-hash"}, up.Strategy),
+			"upstream %s: strategy harus round-robin, least-conn atau consistent-hash, bukan %q", name, up.Strategy)
+		check(validHashKey(up.HashKey), "upstream %s: hash_key harus remote-addr, header:<nama> atau cookie:<nama>, bukan %q", name, up.HashKey)
+		check(up.Retries >= 0, "upstream %s: retries tidak boleh negatif", name)
+		check(up.MaxFails >= 0 && up.FailTimeout >= 0, "upstream %s: max_fails dan fail_timeout tidak boleh negatif", name)
+		check(up.HealthCheck.Path == "" || strings.HasPrefix(up.HealthCheck.Path, "/"),
+			"upstream %s: health_check.path harus diawali \"/\"", name)
+	}
+	check(c.Server.Upstream == "" || slices.Contains(upstreamNames, c.Server.Upstream),
+		"SERVER_UPSTREAM %q tidak didefinisikan di upstreams", c.Server.Upstream)
+
+	for i, route := range c.Policy.Routes {
+		name := route.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		check(slices.Contains([]string{"", "record", "block", "ignore"}, strings.ToLower(route.Action)),
+			"route %s: action harus record, block atau ignore, bukan %q", name, route.Action)
+		check(route.MaxFileSize >= 0, "route %s: max_file_size tidak boleh negatif", name)
+		check(route.Backend == "" || validURL(route.Backend),
+			"route %s: backend harus berupa URL http(s) yang valid, bukan %q", name, route.Backend)
+		check(route.Backend == "" || route.Upstream == "", "route %s: backend dan upstream tidak boleh diisi bersamaan", name)
+		check(route.Upstream == "" || slices.Contains(upstreamNames, route.Upstream),
+			"route %s: upstream %q tidak didefinisikan di upstreams", name, route.Upstream)
+		check(!route.RequireClientCert || c.TLS.ListenAddress != "" && c.TLS.ClientAuth != "none",
+			"route %s: require_client_cert membutuhkan TLS_LISTEN_ADDRESS dan TLS_CLIENT_AUTH request atau require", name)
+		if route.Uploader != "" {
+			for _, err := range validateUploader(c.Uploader, route.Uploader) {
+				errs = append(errs, fmt.Errorf("route %s: %w", name, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// tlsVersions adalah versi TLS yang bisa dipilih di TLS_MIN_VERSION dan TLS_MAX_VERSION, berurutan.
+var tlsVersions = []string{"1.0", "1.1", "1.2", "1.3"}
+
+// validateTLS memeriksa pengaturan listener HTTPS dan ACME.
+func validateTLS(cfg TLSConfig) []error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	if cfg.ListenAddress == "" {
+		check(!cfg.ACME.Enable, "TLS_ACME_ENABLE membutuhkan TLS_LISTEN_ADDRESS")
+		check(cfg.ClientAuth == "none", "TLS_CLIENT_AUTH membutuhkan TLS_LISTEN_ADDRESS")
+		return errs
+	}
+
+	check(cfg.CertFile != "" || len(cfg.Certificates) > 0 || cfg.ACME.Enable,
+		"TLS_LISTEN_ADDRESS membutuhkan TLS_CERT_FILE, tls.certificates atau TLS_ACME_ENABLE")
+	check((cfg.CertFile == "") == (cfg.KeyFile == ""), "TLS_CERT_FILE dan TLS_KEY_FILE harus diisi bersamaan")
+	for i, cert := range cfg.Certificates {
+		check(cert.CertFile != "" && cert.KeyFile != "", "tls.certificates #%d: cert_file dan key_file wajib diisi", i+1)
+	}
+
+	minIdx := slices.Index(tlsVersions, cfg.MinVersion)
+	check(minIdx >= 0, "TLS_MIN_VERSION harus 1.0, 1.1, 1.2 atau 1.3, bukan %q", cfg.MinVersion)
+	if cfg.MaxVersion != "" {
+		maxIdx := slices.Index(tlsVersions, cfg.MaxVersion)
+		check(maxIdx >= 0, "TLS_MAX_VERSION harus 1.0, 1.1, 1.2 atau 1.3, bukan %q", cfg.MaxVersion)
+		check(maxIdx < 0 || maxIdx >= minIdx, "TLS_MAX_VERSION tidak boleh lebih rendah dari TLS_MIN_VERSION")
+	}
+	check(cfg.ReloadInterval >= 0, "TLS_RELOAD_INTERVAL tidak boleh negatif")
+	check(slices.Contains([]string{"none", "request", "require"}, cfg.ClientAuth),
+		"TLS_CLIENT_AUTH harus none, request atau require, bukan %q", cfg.ClientAuth)
+	check(cfg.ClientAuth == "none" || cfg.ClientCAFile != "", "TLS_CLIENT_CA_FILE wajib diisi jika TLS_CLIENT_AUTH=%s", cfg.ClientAuth)
+
+	if cfg.ACME.Enable {
+		check(len(cfg.ACME.Domains) > 0, "TLS_ACME_DOMAINS wajib diisi jika TLS_ACME_ENABLE aktif")
+		check(cfg.ACME.CacheDir != "", "TLS_ACME_CACHE_DIR wajib diisi jika TLS_ACME_ENABLE aktif")
+		check(cfg.ACME.DirectoryURL == "" || validURL(cfg.ACME.DirectoryURL),
+			"TLS_ACME_DIRECTORY_URL harus berupa URL http(s) yang valid, bukan %q", cfg.ACME.DirectoryURL)
+	}
+	return errs
+}
+
+// validateUploader memeriksa pengaturan wajib untuk uploader dengan tipe tertentu.
+func validateUploader(cfg UploaderConfig, uploaderType string) []error {
+	var errs []error
+	switch uploaderType {
+	case "local":
+		if cfg.Local.Path == "" {
+			errs = append(errs, errors.New("UPLOADER_LOCAL_PATH wajib diisi untuk uploader local"))
+		}
+	case "s3":
+		if cfg.S3.Bucket == "" {
+			errs = append(errs, errors.New("UPLOADER_S3_BUCKET wajib diisi untuk uploader s3"))
+		}
+		if cfg.S3.Region == "" {
+			errs = append(errs, errors.New("UPLOADER_S3_REGION wajib diisi untuk uploader s3"))
+		}
+		if cfg.S3.Endpoint != "" && !validURL(cfg.S3.Endpoint) {
+			errs = append(errs, fmt.Errorf("UPLOADER_S3_ENDPOINT harus berupa URL http(s) yang valid, bukan %q", cfg.S3.Endpoint))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("tipe uploader harus local atau s3, bukan %q", uploaderType))
+	}
+	return errs
+}
+
+// validHashKey memeriksa format hash_key upstream consistent-hash.
+func validHashKey(key string) bool {
+	if key == "" || key == "remote-addr" {
+		return true
+	}
+	kind, name, ok := strings.Cut(key, ":")
+	return ok && name != "" && (kind == "header" || kind == "cookie")
+}
+
+// validIPOrCIDR mengembalikan true untuk alamat IP atau prefix CIDR.
+func validIPOrCIDR(entry string) bool {
+	if _, err := netip.ParsePrefix(entry); err == nil {
+		return true
+	}
+	_, err := netip.ParseAddr(entry)
+	return err == nil
+}
+
+// validURL mengembalikan true untuk URL http atau https dengan host.
+func validURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -3,73 +3,242 @@ package detector
 import (
 	"encoding/base64"
 	"fmt"
+	"io"
 	"mime"
 	"net/http"
+	"path/filepath"
 	"regexp"
 	"strings"
+
+	"github.com/luhtaf/corator/buffer"
+	"github.com/luhtaf/corator/config"
+)
+
+// maxFieldSize adalah ukuran maksimal satu field multipart non-file yang diperiksa,
+// sama dengan batas bawaan net/http untuk form value.
+const maxFieldSize = 10 << 20
+
+// Varian encoding base64 yang dicatat di DetectionResult.Encoding.
+const (
+	EncodingBase64    = "base64"    // Alfabet standar (+ dan /)
+	EncodingBase64URL = "base64url" // Alfabet URL-safe (- dan _)
+	variantDataURI    = "data-uri"  // Dibungkus data URI "data:<mime>;base64,"
+	variantUnpadded   = "unpadded"  // Tanpa padding '='
+	variantWrapped    = "wrapped"   // Dipotong per baris (gaya MIME 76 karakter)
 )
 
 // Base64Detector adalah implementasi untuk mendeteksi file dari string Base64.
 type Base64Detector struct {
-	// Regex untuk mencari kandidat string base64.
-	// Minimal 20 karakter, hanya berisi karakter base64, dan mungkin punya padding '='.
+	// Regex untuk mencari kandidat string base64 setelah dinormalisasi.
+	// Minimal 20 karakter dari alfabet standar atau URL-safe, tanpa padding.
 	base64Regex *regexp.Regexp
+	bufferCfg   config.BufferConfig
 }
 
 // NewBase64Detector membuat instance baru dari Base64Detector.
-func NewBase64Detector() *Base64Detector {
+func NewBase64Detector(bufferCfg config.BufferConfig) *Base64Detector {
 	return &Base64Detector{
-		base64Regex: regexp.MustCompile(`^(?:[A-Za-z0-9+/]{4}){5,}(?:[A-Za-z0-9+/]{2}==|[A-Za-z0-9+/]{3}=)?$`),
+		base64Regex: regexp.MustCompile(`^[A-Za-z0-9+/_-]{20,}$`),
+		bufferCfg:   bufferCfg,
 	}
 }
 
-// Detect memeriksa form values dan query params untuk string Base64.
+// Name mengembalikan nama detektor.
+func (d *Base64Detector) Name() string {
+	return "base64"
+}
+
+// Detect memeriksa form values, query params dan field multipart non-file untuk string Base64.
 func (d *Base64Detector) Detect(req *http.Request) ([]DetectionResult, error) {
-	var results []DetectionResult
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/form-data") {
+		return d.detectMultipart(req)
+	}
 
 	// Parse form agar kita bisa mengakses query dan body values
 	if err := req.ParseForm(); err != nil {
 		return nil, fmt.Errorf("gagal parse form: %w", err)
 	}
 
+	var results []DetectionResult
+
 	// Iterasi melalui semua nilai di form (query + body)
 	for fieldName, values := range req.Form {
 		for _, value := range values {
-			// Cek apakah value ini adalah kandidat base64
-			if !d.base64Regex.MatchString(value) {
-				continue
+			if result, ok := d.detectValue(fieldName, value); ok {
+				results = append(results, result)
 			}
+		}
+	}
 
-			// Coba decode string base64
-			decodedData, err := base64.StdEncoding.DecodeString(value)
-			if err != nil {
-				continue // Bukan base64 yang valid, abaikan.
-			}
+	return results, nil
+}
 
-			// Cek tipe konten dari data yang sudah di-decode
-			mimeType := http.DetectContentType(decodedData)
+// detectMultipart memeriksa query params dan field non-file dari body multipart
+// secara streaming, part demi part.
+func (d *Base64Detector) detectMultipart(req *http.Request) ([]DetectionResult, error) {
+	var results []DetectionResult
 
-			// Abaikan jika hanya teks biasa
-			if strings.HasPrefix(mimeType, "text/plain") {
-				continue
+	for fieldName, values := range req.URL.Query() {
+		for _, value := range values {
+			if result, ok := d.detectValue(fieldName, value); ok {
+				results = append(results, result)
 			}
+		}
+	}
 
-			// Buat nama file yang unik dan dapat dilacak
-			exts, _ := mime.ExtensionsByType(mimeType)
-			ext := ".bin" // default extension
-			if len(exts) > 0 {
-				ext = exts[0]
-			}
-			fileName := fmt.Sprintf("base64_%s%s", fieldName, ext)
-
-			results = append(results, DetectionResult{
-				Data:        decodedData,
-				FileName:    fileName,
-				SourceField: "form-field:" + fieldName,
-				MimeType:    mimeType,
-			})
+	reader, err := req.MultipartReader()
+	if err != nil {
+		return results, fmt.Errorf("gagal membaca multipart: %w", err)
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return results, fmt.Errorf("gagal membaca part multipart: %w", err)
+		}
+
+		// Part file ditangani oleh FileDetector
+		if part.FileName() != "" {
+			part.Close()
+			continue
+		}
+
+		value, err := io.ReadAll(io.LimitReader(part, maxFieldSize+1))
+		fieldName := part.FormName()
+		part.Close()
+		if err != nil || len(value) > maxFieldSize {
+			continue // Field terlalu besar atau gagal dibaca, abaikan.
+		}
+
+		if result, ok := d.detectValue(fieldName, string(value)); ok {
+			results = append(results, result)
 		}
 	}
 
 	return results, nil
 }
+
+// detectValue memeriksa satu nilai field dan men-decode-nya ke buffer jika berisi file.
+func (d *Base64Detector) detectValue(fieldName, value string) (DetectionResult, bool) {
+	result, ok := d.decodeValue(fieldName, value)
+	if !ok {
+		return DetectionResult{}, false
+	}
+
+	result.FileName = base64FileName(fieldName, result.MimeType)
+	result.SourceField = "form-field:" + fieldName
+	return result, true
+}
+
+// decodeValue men-decode value ke buffer jika value adalah string base64 yang berisi file.
+// name adalah nama field atau key JSON, yang ekstensinya ikut dicocokkan dengan magic bytes.
+// Hasilnya berisi Content, MimeType, DeclaredMimeType, Encoding dan Mismatches; pemanggil
+// mengisi FileName dan SourceField serta bertanggung jawab menutup buffer-nya.
+func (d *Base64Detector) decodeValue(name, value string) (DetectionResult, bool) {
+	payload, declaredMime, variants := normalizeBase64(value)
+
+	// Cek apakah payload ini adalah kandidat base64
+	if !d.base64Regex.MatchString(payload) || len(payload)%4 == 1 {
+		return DetectionResult{}, false
+	}
+
+	// Alfabet standar dan URL-safe tidak boleh tercampur
+	encoding := base64.RawStdEncoding
+	variant := EncodingBase64
+	if strings.ContainsAny(payload, "-_") {
+		if strings.ContainsAny(payload, "+/") {
+			return DetectionResult{}, false
+		}
+		encoding = base64.RawURLEncoding
+		variant = EncodingBase64URL
+	}
+
+	// Decode string base64 langsung ke buffer
+	content := buffer.NewFromConfig(d.bufferCfg)
+	if _, err := io.Copy(content, base64.NewDecoder(encoding, strings.NewReader(payload))); err != nil {
+		content.Close()
+		return DetectionResult{}, false // Bukan base64 yang valid, abaikan.
+	}
+
+	// Abaikan jika hanya teks biasa, kecuali data URI yang memang menyatakan dirinya file
+	if declaredMime == "" && strings.HasPrefix(http.DetectContentType(content.Head(512)), "text/plain") {
+		content.Close()
+		return DetectionResult{}, false
+	}
+
+	detectedMime, mismatches := verifyContent(content, name, declaredMime)
+	return DetectionResult{
+		Content:          content,
+		MimeType:         resolveMimeType(declaredMime, detectedMime),
+		DeclaredMimeType: declaredMime,
+		Encoding:         strings.Join(append([]string{variant}, variants...), "+"),
+		Mismatches:       mismatches,
+	}, true
+}
+
+// normalizeBase64 melepas prefix data URI, pemotongan baris dan padding dari value,
+// lalu mengembalikan payload, tipe MIME yang dinyatakan data URI (jika ada) dan
+// daftar varian encoding yang ditemukan.
+func normalizeBase64(value string) (payload, declaredMime string, variants []string) {
+	payload = value
+
+	if rest, ok := strings.CutPrefix(payload, "data:"); ok {
+		meta, data, found := strings.Cut(rest, ",")
+		params := strings.Split(meta, ";")
+		if found && params[len(params)-1] == "base64" {
+			payload = data
+			declaredMime = strings.ToLower(strings.TrimSpace(params[0]))
+			variants = append(variants, variantDataURI)
+		}
+	}
+
+	if strings.ContainsAny(payload, "\r\n") {
+		payload = strings.NewReplacer("\r", "", "\n", "").Replace(payload)
+		variants = append(variants, variantWrapped)
+	}
+
+	trimmed := strings.TrimRight(payload, "=")
+	if trimmed == payload && len(payload)%4 != 0 {
+		variants = append(variants, variantUnpadded)
+	}
+
+	return trimmed, declaredMime, variants
+}
+
+// resolveMimeType memilih tipe MIME hasil deteksi. Tipe yang dinyatakan data URI
+// dipakai jika sniffing mengonfirmasinya atau tidak bisa menentukan tipe; jika sniffing
+// menemukan tipe lain, hasil sniffing yang dipakai karena deklarasi berasal dari client.
+func resolveMimeType(declaredMime, sniffedMime string) string {
+	if declaredMime == "" {
+		return sniffedMime
+	}
+
+	sniffedBase, _, _ := strings.Cut(sniffedMime, ";")
+	if sniffedBase == declaredMime || sniffedBase == "application/octet-stream" || sniffedBase == "text/plain" {
+		return declaredMime
+	}
+	return sniffedMime
+}
+
+// base64FileName membuat nama file yang unik dan dapat dilacak dari nama field dan tipe MIME.
+// Nama field berasal dari client, jadi separator path dan ".." dibuang agar nama file
+// tidak bisa keluar dari direktori tujuan.
+func base64FileName(fieldName, mimeType string) string {
+	exts, _ := mime.ExtensionsByType(mimeType)
+	ext := ".bin" // default extension
+	if len(exts) > 0 {
+		ext = exts[0]
+	}
+
+	safe := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == 0 {
+			return '_'
+		}
+		return r
+	}, fieldName)
+	safe = strings.ReplaceAll(safe, "..", "_")
+	return filepath.Base(fmt.Sprintf("base64_%s%s", safe, ext))
+}
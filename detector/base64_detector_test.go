@@ -0,0 +1,174 @@
+package detector
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/luhtaf/corator/config"
+)
+
+// pngBase64 adalah PNG 1x1 yang di-encode base64.
+const pngBase64 = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNk+M9QDwADhgGAWjR9awAAAABJRU5ErkJggg=="
+
+func TestBase64FileNameStripsPathTraversal(t *testing.T) {
+	for _, field := range []string{"a/../../../../tmp/pwn", `..\..\windows\pwn`, "../", "..", "x\x00/y"} {
+		name := base64FileName(field, "image/png")
+		if strings.ContainsAny(name, `/\`+"\x00") || strings.Contains(name, "..") {
+			t.Errorf("base64FileName(%q) = %q, masih berisi separator path atau \"..\"", field, name)
+		}
+	}
+}
+
+func TestJSONDetectorSanitizesKeyFileName(t *testing.T) {
+	body := `{"a/../../../../tmp/pwn": "` + pngBase64 + `"}`
+	req := httptest.NewRequest("POST", "/upload", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	results, err := NewJSONDetector(config.BufferConfig{MemoryLimit: 1 << 20, TempDir: t.TempDir()}).Detect(req)
+	if err != nil {
+		t.Fatalf("Detect: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("jumlah file = %d, seharusnya 1", len(results))
+	}
+	defer results[0].Close()
+
+	if name := results[0].FileName; strings.Contains(name, "/") || strings.Contains(name, "..") {
+		t.Errorf("nama file %q masih berisi path dari key JSON", name)
+	}
+}
+
+// elfHeader adalah awal executable ELF 64-bit little-endian (e_type ET_EXEC).
+var elfHeader = append([]byte("\x7fELF\x02\x01\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02\x00\x3e\x00"), make([]byte, 108)...)
+
+func TestDisguisedExecutableFlagged(t *testing.T) {
+	pe := base64.StdEncoding.EncodeToString(peHeader)
+	elf := base64.StdEncoding.EncodeToString(elfHeader)
+	bufferCfg := config.BufferConfig{MemoryLimit: 1 << 20, TempDir: t.TempDir()}
+
+	tests := []struct {
+		name     string
+		detector Detector
+		req      *http.Request
+		want     []string
+	}{
+		{
+			"field form bernama invoice.pdf", NewBase64Detector(bufferCfg),
+			newRawRequest("POST", "/upload", "application/x-www-form-urlencoded", []byte(url.Values{"invoice.pdf": {pe}}.Encode())),
+			[]string{MismatchExtension},
+		},
+		{
+			"data URI application/pdf", NewBase64Detector(bufferCfg),
+			newRawRequest("POST", "/upload", "application/x-www-form-urlencoded", []byte(url.Values{"invoice": {"data:application/pdf;base64," + elf}}.Encode())),
+			[]string{MismatchContentType},
+		},
+		{
+			"key JSON invoice.pdf", NewJSONDetector(bufferCfg),
+			newRawRequest("POST", "/api", "application/json", []byte(`{"invoice.pdf": "data:application/pdf;base64,`+pe+`"}`)),
+			[]string{MismatchContentType, MismatchExtension},
+		},
+		{
+			"body mentah invoice.pdf", NewRawDetector([]string{"application/pdf"}, bufferCfg),
+			newRawRequest("PUT", "/objects/invoice.pdf", "application/pdf", elfHeader),
+			[]string{MismatchContentType, MismatchExtension},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := tt.detector.Detect(tt.req)
+			if err != nil {
+				t.Fatalf("Detect: %v", err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("jumlah file = %d, seharusnya 1", len(results))
+			}
+			defer results[0].Close()
+
+			if got := results[0].MimeType; got == "application/pdf" {
+				t.Errorf("MimeType = %q, seharusnya hasil magic bytes", got)
+			}
+			if !slices.Equal(results[0].Mismatches, tt.want) {
+				t.Errorf("Mismatches = %v, seharusnya %v", results[0].Mismatches, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeValueVariants(t *testing.T) {
+	png, _ := base64.StdEncoding.DecodeString(pngBase64)
+	wrapped := pngBase64[:76] + "\r\n" + pngBase64[76:]
+	d := NewBase64Detector(config.BufferConfig{MemoryLimit: 1 << 20, TempDir: t.TempDir()})
+
+	tests := []struct {
+		name         string
+		value        string
+		wantEncoding string
+		wantDeclared string
+	}{
+		{"standar", pngBase64, "base64", ""},
+		{"data URI", "data:image/png;base64," + pngBase64, "base64+data-uri", "image/png"},
+		{"data URI dengan parameter", "data:IMAGE/PNG;name=a.png;base64," + pngBase64, "base64+data-uri", "image/png"},
+		{"base64url", base64.URLEncoding.EncodeToString(png), "base64url", ""},
+		{"base64url tanpa padding", base64.RawURLEncoding.EncodeToString(png), "base64url+unpadded", ""},
+		{"tanpa padding", base64.RawStdEncoding.EncodeToString(png), "base64+unpadded", ""},
+		{"dipotong per baris", wrapped, "base64+wrapped", ""},
+		{"dipotong per baris dengan LF", strings.ReplaceAll(wrapped, "\r", ""), "base64+wrapped", ""},
+		{"gabungan", "data:image/png;base64," + strings.TrimRight(wrapped, "="), "base64+data-uri+wrapped+unpadded", "image/png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := d.decodeValue("foto", tt.value)
+			if !ok {
+				t.Fatalf("decodeValue(%q) tidak mendeteksi file", tt.value)
+			}
+			defer result.Close()
+
+			if result.Encoding != tt.wantEncoding {
+				t.Errorf("Encoding = %q, seharusnya %q", result.Encoding, tt.wantEncoding)
+			}
+			if result.DeclaredMimeType != tt.wantDeclared {
+				t.Errorf("DeclaredMimeType = %q, seharusnya %q", result.DeclaredMimeType, tt.wantDeclared)
+			}
+			if result.MimeType != "image/png" {
+				t.Errorf("MimeType = %q, seharusnya image/png", result.MimeType)
+			}
+			got, _ := io.ReadAll(result.Content.Reader())
+			if !bytes.Equal(got, png) {
+				t.Errorf("hasil decode berbeda dari PNG asli (%d byte, seharusnya %d)", len(got), len(png))
+			}
+		})
+	}
+}
+
+func TestDecodeValueRejectsNearMisses(t *testing.T) {
+	d := NewBase64Detector(config.BufferConfig{MemoryLimit: 1 << 20, TempDir: t.TempDir()})
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"alfabet tercampur", strings.Replace(pngBase64, "A", "-", 1)},
+		{"panjang sisa 1", pngBase64[:len(pngBase64)-3]},
+		{"karakter asing", pngBase64[:40] + "!" + pngBase64[41:]},
+		{"padding di tengah", pngBase64[:40] + "==" + pngBase64[40:]},
+		{"terlalu pendek", "iVBORw0KGgo="},
+		{"teks biasa", base64.StdEncoding.EncodeToString([]byte("ini hanya kalimat biasa, bukan file"))},
+		{"data URI tanpa base64", "data:image/png," + pngBase64},
+		{"spasi di dalam", pngBase64[:40] + " " + pngBase64[40:]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result, ok := d.decodeValue("foto", tt.value); ok {
+				result.Close()
+				t.Errorf("decodeValue(%q) seharusnya tidak di-decode, mendapat %q", tt.value, result.MimeType)
+			}
+		})
+	}
+}
@@ -12,12 +12,22 @@ func NewDetectors(cfg *config.Config) []Detector {
 
 	if cfg.Detectors.EnableFile {
 		log.Println("FileDetector aktif.")
-		activeDetectors = append(activeDetectors, NewFileDetector())
+		activeDetectors = append(activeDetectors, NewFileDetector(cfg.Buffer))
 	}
 
 	if cfg.Detectors.EnableBase64 {
 		log.Println("Base64Detector aktif.")
-		activeDetectors = append(activeDetectors, NewBase64Detector())
+		activeDetectors = append(activeDetectors, NewBase64Detector(cfg.Buffer))
+	}
+
+	if cfg.Detectors.EnableJSON {
+		log.Println("JSONDetector aktif.")
+		activeDetectors = append(activeDetectors, NewJSONDetector(cfg.Buffer))
+	}
+
+	if cfg.Detectors.EnableRaw {
+		log.Println("RawDetector aktif.")
+		activeDetectors = append(activeDetectors, NewRawDetector(cfg.Detectors.RawAllowedTypes, cfg.Buffer))
 	}
 
 	return activeDetectors
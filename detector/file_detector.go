@@ -1,21 +1,33 @@
 package detector
 
 import (
+	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"strings"
+
+	"github.com/luhtaf/corator/buffer"
+	"github.com/luhtaf/corator/config"
 )
 
 // FileDetector adalah implementasi untuk mendeteksi file dari multipart/form-data.
-type FileDetector struct{}
+type FileDetector struct {
+	bufferCfg config.BufferConfig
+}
 
 // NewFileDetector membuat instance baru dari FileDetector.
-func NewFileDetector() *FileDetector {
-	return &FileDetector{}
+func NewFileDetector(bufferCfg config.BufferConfig) *FileDetector {
+	return &FileDetector{bufferCfg: bufferCfg}
+}
+
+// Name mengembalikan nama detektor.
+func (d *FileDetector) Name() string {
+	return "file"
 }
 
-// Detect memeriksa request untuk file upload.
+// Detect memeriksa request untuk file upload. Body dibaca part demi part
+// sehingga penggunaan memori tetap terbatas berapa pun ukuran upload.
 func (d *FileDetector) Detect(req *http.Request) ([]DetectionResult, error) {
 	// Cek apakah requestnya adalah multipart/form-data
 	contentType := req.Header.Get("Content-Type")
@@ -23,55 +35,62 @@ func (d *FileDetector) Detect(req *http.Request) ([]DetectionResult, error) {
 		return nil, nil // Bukan file upload, tidak ada yang dideteksi.
 	}
 
+	reader, err := req.MultipartReader()
+	if err != nil {
+		if err == http.ErrNotMultipart {
+			return nil, nil
+		}
+		return nil, err
+	}
+
 	var results []DetectionResult
 
-	// Batasi ukuran form di memori (misal: 32MB), sisanya akan ditulis ke disk sementara.
-	if err := req.ParseMultipartForm(32 << 20); err != nil {
-		// Jika error karena body terlalu besar, kita bisa abaikan, WAF mungkin akan menanganinya.
-		// Jika error lain, kita kembalikan errornya.
-		if err != http.ErrNotMultipart {
-			return nil, err
+	// Iterasi semua part, hanya part berupa file yang diproses
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// Kembalikan file yang sudah berhasil dibaca bersama errornya
+			return results, fmt.Errorf("gagal membaca part multipart: %w", err)
 		}
-		return nil, nil
-	}
 
-	// Jika tidak ada form, tidak ada yang perlu diperiksa
-	if req.MultipartForm == nil || req.MultipartForm.File == nil {
-		return nil, nil
-	}
+		if part.FileName() == "" {
+			part.Close()
+			continue
+		}
 
-	// Iterasi semua file yang di-upload
-	for fieldName, fileHeaders := range req.MultipartForm.File {
-		for _, fileHeader := range fileHeaders {
-			result, err := d.processFile(fieldName, fileHeader)
-			if err != nil {
-				// Mungkin logging di sini lebih baik daripada menghentikan proses
-				continue
-			}
-			results = append(results, result)
+		result, err := d.processPart(part)
+		part.Close()
+		if err != nil {
+			// Mungkin logging di sini lebih baik daripada menghentikan proses
+			continue
 		}
+		results = append(results, result)
 	}
 
 	return results, nil
 }
 
-// processFile memproses satu file header menjadi DetectionResult.
-func (d *FileDetector) processFile(fieldName string, fileHeader *multipart.FileHeader) (DetectionResult, error) {
-	file, err := fileHeader.Open()
-	if err != nil {
+// processPart menyalin satu part file ke buffer dan menjadikannya DetectionResult.
+// Tipe MIME diambil dari magic bytes, bukan dari Content-Type part yang bisa dipalsukan.
+func (d *FileDetector) processPart(part *multipart.Part) (DetectionResult, error) {
+	content := buffer.NewFromConfig(d.bufferCfg)
+	if _, err := io.Copy(content, part); err != nil {
+		content.Close()
 		return DetectionResult{}, err
 	}
-	defer file.Close()
 
-	data, err := io.ReadAll(file)
-	if err != nil {
-		return DetectionResult{}, err
-	}
+	declaredMime := part.Header.Get("Content-Type")
+	mimeType, mismatches := verifyContent(content, part.FileName(), declaredMime)
 
 	return DetectionResult{
-		Data:        data,
-		FileName:    fileHeader.Filename,
-		SourceField: "multipart-field:" + fieldName,
-		MimeType:    fileHeader.Header.Get("Content-Type"),
+		Content:          content,
+		FileName:         part.FileName(),
+		SourceField:      "multipart-field:" + part.FormName(),
+		MimeType:         mimeType,
+		DeclaredMimeType: declaredMime,
+		Mismatches:       mismatches,
 	}, nil
 }
@@ -0,0 +1,129 @@
+package detector
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/luhtaf/corator/config"
+)
+
+// maxJSONDepth adalah kedalaman nesting maksimal body JSON yang ditelusuri.
+const maxJSONDepth = 64
+
+// jsonIdentifier adalah pola key JSON yang bisa ditulis dengan notasi titik di JSON path.
+var jsonIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// JSONDetector mendeteksi file yang di-encode sebagai Base64 di dalam body JSON,
+// termasuk di dalam object dan array bersarang.
+type JSONDetector struct {
+	base64 *Base64Detector
+}
+
+// NewJSONDetector membuat instance baru dari JSONDetector.
+func NewJSONDetector(bufferCfg config.BufferConfig) *JSONDetector {
+	return &JSONDetector{base64: NewBase64Detector(bufferCfg)}
+}
+
+// Name mengembalikan nama detektor.
+func (d *JSONDetector) Name() string {
+	return "json"
+}
+
+// Detect menelusuri body JSON secara streaming dan memeriksa setiap string untuk Base64.
+func (d *JSONDetector) Detect(req *http.Request) ([]DetectionResult, error) {
+	if !isJSONContentType(req.Header.Get("Content-Type")) {
+		return nil, nil
+	}
+
+	dec := json.NewDecoder(req.Body)
+	dec.UseNumber()
+
+	var results []DetectionResult
+	if err := d.walk(dec, "$", "value", 0, &results); err != nil && err != io.EOF {
+		return results, fmt.Errorf("gagal membaca body JSON: %w", err)
+	}
+
+	return results, nil
+}
+
+// walk membaca satu nilai JSON dari decoder. path adalah JSON path nilai tersebut,
+// key adalah key object terdekat yang dipakai untuk nama file.
+func (d *JSONDetector) walk(dec *json.Decoder, path, key string, depth int, results *[]DetectionResult) error {
+	if depth > maxJSONDepth {
+		return fmt.Errorf("nesting JSON melebihi %d level", maxJSONDepth)
+	}
+
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			for dec.More() {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				k, _ := keyTok.(string)
+				if err := d.walk(dec, path+jsonPathKey(k), k, depth+1, results); err != nil {
+					return err
+				}
+			}
+		case '[':
+			for i := 0; dec.More(); i++ {
+				if err := d.walk(dec, fmt.Sprintf("%s[%d]", path, i), key, depth+1, results); err != nil {
+					return err
+				}
+			}
+		}
+		// Konsumsi delimiter penutup '}' atau ']'
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+
+	case string:
+		if result, ok := d.detectString(path, key, t); ok {
+			*results = append(*results, result)
+		}
+	}
+
+	return nil
+}
+
+// detectString memeriksa satu string JSON untuk Base64, termasuk data URI.
+func (d *JSONDetector) detectString(path, key, value string) (DetectionResult, bool) {
+	result, ok := d.base64.decodeValue(key, value)
+	if !ok {
+		return DetectionResult{}, false
+	}
+
+	result.FileName = base64FileName(key, result.MimeType)
+	result.SourceField = "json:" + path
+	return result, true
+}
+
+// jsonPathKey menulis satu key object sebagai segmen JSON path.
+func jsonPathKey(key string) string {
+	if jsonIdentifier.MatchString(key) {
+		return "." + key
+	}
+	return "[" + strconv.Quote(key) + "]"
+}
+
+// isJSONContentType mengembalikan true untuk application/json dan tipe +json.
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
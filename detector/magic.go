@@ -0,0 +1,58 @@
+package detector
+
+import (
+	"mime"
+	"path/filepath"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/luhtaf/corator/buffer"
+)
+
+// Jenis ketidaksesuaian yang dicatat di DetectionResult.Mismatches.
+const (
+	MismatchContentType = "content-type" // Content-Type dari client tidak sesuai dengan magic bytes
+	MismatchExtension   = "extension"    // Ekstensi nama file tidak sesuai dengan magic bytes
+)
+
+// verifyContent mendeteksi tipe MIME sebenarnya dari magic bytes dan membandingkannya
+// dengan tipe dan nama file yang dinyatakan client, misalnya invoice.pdf yang
+// ternyata executable PE.
+func verifyContent(content *buffer.SpillBuffer, fileName, declaredMime string) (string, []string) {
+	detected, err := mimetype.DetectReader(content.Reader())
+	if err != nil {
+		detected = mimetype.Lookup("application/octet-stream")
+	}
+
+	var mismatches []string
+
+	declaredMime, _, _ = strings.Cut(strings.ToLower(declaredMime), ";")
+	declaredMime = strings.TrimSpace(declaredMime)
+	if declaredMime != "" && declaredMime != "application/octet-stream" && !isMIME(detected, declaredMime) {
+		mismatches = append(mismatches, MismatchContentType)
+	}
+
+	if ext := strings.ToLower(filepath.Ext(fileName)); ext != "" && ext != detected.Extension() {
+		if extMime, _, _ := strings.Cut(mime.TypeByExtension(ext), ";"); extMime != "" {
+			if !isMIME(detected, extMime) {
+				mismatches = append(mismatches, MismatchExtension)
+			}
+		} else if detected.Extension() != "" && !isMIME(detected, "text/plain") {
+			// Ekstensi tidak dikenal tabel MIME sistem, bandingkan dengan ekstensi hasil deteksi
+			mismatches = append(mismatches, MismatchExtension)
+		}
+	}
+
+	return detected.String(), mismatches
+}
+
+// isMIME mengembalikan true jika tipe hasil deteksi atau salah satu induknya
+// (misalnya text/plain untuk text/csv) sama dengan expected.
+func isMIME(detected *mimetype.MIME, expected string) bool {
+	for m := detected; m != nil; m = m.Parent() {
+		if m.Is(expected) {
+			return true
+		}
+	}
+	return false
+}
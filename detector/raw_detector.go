@@ -0,0 +1,158 @@
+package detector
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/luhtaf/corator/buffer"
+	"github.com/luhtaf/corator/config"
+)
+
+// RawDetector mendeteksi file yang dikirim langsung sebagai body request tanpa
+// multipart, misalnya upload gaya S3 (PUT /objects/x), POST application/octet-stream
+// dan upload resumable tus (PATCH application/offset+octet-stream).
+type RawDetector struct {
+	allowedTypes []string
+	bufferCfg    config.BufferConfig
+}
+
+// NewRawDetector membuat instance baru dari RawDetector. allowedTypes berisi tipe MIME
+// yang ditangkap, mendukung wildcard seperti "image/*".
+func NewRawDetector(allowedTypes []string, bufferCfg config.BufferConfig) *RawDetector {
+	normalized := make([]string, 0, len(allowedTypes))
+	for _, t := range allowedTypes {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			normalized = append(normalized, t)
+		}
+	}
+	return &RawDetector{allowedTypes: normalized, bufferCfg: bufferCfg}
+}
+
+// Name mengembalikan nama detektor.
+func (d *RawDetector) Name() string {
+	return "raw"
+}
+
+// Detect menangkap body request jika tipe yang dinyatakan atau hasil sniffing
+// ada di allow-list.
+func (d *RawDetector) Detect(req *http.Request) ([]DetectionResult, error) {
+	switch req.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		return nil, nil
+	}
+
+	declaredMime, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+
+	// Form dan multipart ditangani oleh detektor lain
+	if strings.HasPrefix(declaredMime, "multipart/") || declaredMime == "application/x-www-form-urlencoded" {
+		return nil, nil
+	}
+
+	content := buffer.NewFromConfig(d.bufferCfg)
+	if _, err := io.Copy(content, req.Body); err != nil {
+		content.Close()
+		return nil, fmt.Errorf("gagal membaca body request: %w", err)
+	}
+	if content.Size() == 0 {
+		content.Close()
+		return nil, nil
+	}
+
+	// Content-Type tus hanya menyatakan format transfer, bukan tipe file
+	claimedMime := declaredMime
+	if claimedMime == "application/offset+octet-stream" {
+		claimedMime = ""
+	}
+	fileName := rawFileName(req)
+	mimeType, mismatches := verifyContent(content, fileName, claimedMime)
+	if !d.allowed(declaredMime) && !d.allowed(mimeType) {
+		content.Close()
+		return nil, nil
+	}
+
+	if fileName == "" {
+		fileName = generatedRawFileName(mimeType)
+	}
+	return []DetectionResult{{
+		Content:          content,
+		FileName:         fileName,
+		SourceField:      "raw-body",
+		MimeType:         mimeType,
+		DeclaredMimeType: declaredMime,
+		Mismatches:       mismatches,
+	}}, nil
+}
+
+// allowed mengembalikan true jika tipe MIME cocok dengan salah satu entri allow-list.
+func (d *RawDetector) allowed(mimeType string) bool {
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+	if mimeType == "" {
+		return false
+	}
+	for _, t := range d.allowedTypes {
+		if t == mimeType || t == "*/*" {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(t, "/*"); ok && strings.HasPrefix(mimeType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// rawFileName menentukan nama file dari Content-Disposition, metadata tus atau segmen
+// terakhir URL path, dan mengembalikan "" jika client tidak menyatakan nama file.
+func rawFileName(req *http.Request) string {
+	if _, params, err := mime.ParseMediaType(req.Header.Get("Content-Disposition")); err == nil {
+		if name := baseFileName(params["filename"]); name != "" {
+			return name
+		}
+	}
+
+	// Upload-Metadata tus berbentuk "key base64value,key base64value"
+	for _, pair := range strings.Split(req.Header.Get("Upload-Metadata"), ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key != "filename" {
+			continue
+		}
+		if name, err := base64.StdEncoding.DecodeString(value); err == nil {
+			if name := baseFileName(string(name)); name != "" {
+				return name
+			}
+		}
+	}
+
+	if base := baseFileName(req.URL.Path); strings.Contains(base, ".") {
+		return base
+	}
+	return ""
+}
+
+// baseFileName mengambil segmen terakhir dari nama file yang dinyatakan client. Client
+// Windows mengirim path dengan "\" (misalnya C:\x\evil.exe), jadi kedua separator dibuang.
+func baseFileName(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if name == "." || name == ".." || strings.ContainsRune(name, 0) {
+		return ""
+	}
+	return name
+}
+
+// generatedRawFileName membuat nama file dari tipe MIME untuk body tanpa nama file.
+func generatedRawFileName(mimeType string) string {
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+	exts, _ := mime.ExtensionsByType(mimeType)
+	ext := ".bin"
+	if len(exts) > 0 {
+		ext = exts[0]
+	}
+	return "raw_body" + ext
+}
@@ -0,0 +1,122 @@
+package detector
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/luhtaf/corator/config"
+)
+
+// peHeader adalah awal executable PE (DOS stub "MZ").
+var peHeader = append([]byte("MZ"), make([]byte, 126)...)
+
+func newRawRequest(method, target, contentType string, body []byte) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(string(body)))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req
+}
+
+func detectRaw(t *testing.T, allowed []string, req *http.Request) []DetectionResult {
+	t.Helper()
+	results, err := NewRawDetector(allowed, config.BufferConfig{MemoryLimit: 1 << 20, TempDir: t.TempDir()}).Detect(req)
+	if err != nil {
+		t.Fatalf("Detect: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, r := range results {
+			r.Close()
+		}
+	})
+	return results
+}
+
+func TestRawDetectorSkipsNonUploads(t *testing.T) {
+	png, _ := base64.StdEncoding.DecodeString(pngBase64)
+	tests := []struct {
+		name string
+		req  *http.Request
+	}{
+		{"GET", newRawRequest("GET", "/objects/a.png", "image/png", png)},
+		{"multipart", newRawRequest("POST", "/upload", "multipart/form-data; boundary=x", png)},
+		{"form", newRawRequest("POST", "/login", "application/x-www-form-urlencoded", []byte("user=a"))},
+		{"body kosong", newRawRequest("PUT", "/objects/a.png", "image/png", nil)},
+		{"di luar allow-list", newRawRequest("POST", "/api", "application/json", []byte(`{"a":1}`))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if results := detectRaw(t, []string{"image/*"}, tt.req); len(results) != 0 {
+				t.Errorf("jumlah file = %d, seharusnya 0", len(results))
+			}
+		})
+	}
+}
+
+func TestRawDetectorFileName(t *testing.T) {
+	png, _ := base64.StdEncoding.DecodeString(pngBase64)
+	tests := []struct {
+		name    string
+		target  string
+		headers map[string]string
+		want    string
+	}{
+		{"segmen URL", "/objects/foto.png", nil, "foto.png"},
+		{"Content-Disposition", "/upload", map[string]string{"Content-Disposition": `attachment; filename="../../foto.png"`}, "foto.png"},
+		{"path Windows", "/upload", map[string]string{"Content-Disposition": `attachment; filename="C:\\x\\evil.exe"`}, "evil.exe"},
+		{"metadata tus", "/files/abc", map[string]string{"Upload-Metadata": "filetype aW1hZ2UvcG5n,filename " + base64.StdEncoding.EncodeToString([]byte(`..\..\foto.png`))}, "foto.png"},
+		{"tanpa nama", "/upload", nil, "raw_body.png"},
+		{"dot-dot", "/objects/..", map[string]string{"Content-Disposition": `attachment; filename=".."`}, "raw_body.png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newRawRequest("PUT", tt.target, "application/octet-stream", png)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			results := detectRaw(t, []string{"image/*"}, req)
+			if len(results) != 1 {
+				t.Fatalf("jumlah file = %d, seharusnya 1", len(results))
+			}
+			if got := results[0].FileName; got != tt.want {
+				t.Errorf("nama file = %q, seharusnya %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRawDetectorFlagsDisguisedExecutable(t *testing.T) {
+	req := newRawRequest("PUT", "/objects/invoice.pdf", "application/pdf", peHeader)
+	results := detectRaw(t, []string{"application/pdf"}, req)
+	if len(results) != 1 {
+		t.Fatalf("jumlah file = %d, seharusnya 1", len(results))
+	}
+
+	r := results[0]
+	if r.MimeType != "application/vnd.microsoft.portable-executable" {
+		t.Errorf("MimeType = %q, seharusnya hasil magic bytes", r.MimeType)
+	}
+	if r.DeclaredMimeType != "application/pdf" {
+		t.Errorf("DeclaredMimeType = %q, seharusnya application/pdf", r.DeclaredMimeType)
+	}
+	for _, want := range []string{MismatchContentType, MismatchExtension} {
+		if !slices.Contains(r.Mismatches, want) {
+			t.Errorf("Mismatches = %v, seharusnya memuat %q", r.Mismatches, want)
+		}
+	}
+}
+
+func TestRawDetectorAllowsBySniffedType(t *testing.T) {
+	png, _ := base64.StdEncoding.DecodeString(pngBase64)
+	results := detectRaw(t, []string{"image/*"}, newRawRequest("PATCH", "/files/abc", "application/offset+octet-stream", png))
+	if len(results) != 1 {
+		t.Fatalf("jumlah file = %d, seharusnya 1", len(results))
+	}
+	if results[0].MimeType != "image/png" || len(results[0].Mismatches) != 0 {
+		t.Errorf("MimeType = %q, Mismatches = %v", results[0].MimeType, results[0].Mismatches)
+	}
+}
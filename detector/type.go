@@ -1,16 +1,36 @@
 package detector
 
-import "net/http"
+import (
+	"net/http"
+
+	"github.com/luhtaf/corator/buffer"
+)
 
 // DetectionResult menampung informasi tentang file yang ditemukan.
 type DetectionResult struct {
-	Data        []byte // Konten file setelah di-decode
-	FileName    string // Nama file asli atau hasil generate
-	SourceField string // Field tempat file ditemukan (e.g., "form-field:user_avatar")
-	MimeType    string // Tipe MIME dari data
+	Content     *buffer.SpillBuffer // Konten file setelah di-decode, di memori atau file sementara
+	FileName    string              // Nama file asli atau hasil generate
+	SourceField string              // Field tempat file ditemukan (e.g., "form-field:user_avatar")
+	Detector    string              // Nama detektor yang menemukan file, diisi oleh handler
+	MimeType    string              // Tipe MIME dari data
+
+	DeclaredMimeType string   // Tipe MIME yang dinyatakan client (e.g., dari data URI), jika ada
+	Encoding         string   // Varian encoding yang ditemukan (e.g., "base64url+unpadded"), kosong untuk file biasa
+	Mismatches       []string // Ketidaksesuaian nama file/Content-Type dengan magic bytes (e.g., "extension")
+}
+
+// Close melepaskan buffer konten hasil deteksi.
+func (r DetectionResult) Close() error {
+	if r.Content == nil {
+		return nil
+	}
+	return r.Content.Close()
 }
 
 // Detector adalah interface umum untuk semua implementasi detektor.
+// Body request dibaca secara streaming; setiap detektor menerima req.Body yang baru.
 type Detector interface {
+	// Name mengembalikan nama detektor, dipakai untuk log dan metrik.
+	Name() string
 	Detect(req *http.Request) ([]DetectionResult, error)
 }
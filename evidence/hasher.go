@@ -0,0 +1,59 @@
+package evidence
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+)
+
+// Hashes menampung digest kriptografis (hex) dari sebuah file bukti.
+type Hashes struct {
+	MD5    string `json:"md5"`
+	SHA1   string `json:"sha1"`
+	SHA256 string `json:"sha256"`
+}
+
+// Hasher menghitung MD5, SHA-1 dan SHA-256 sekaligus dalam satu kali baca.
+type Hasher struct {
+	md5    hash.Hash
+	sha1   hash.Hash
+	sha256 hash.Hash
+	writer io.Writer
+}
+
+// NewHasher membuat instance baru dari Hasher.
+func NewHasher() *Hasher {
+	h := &Hasher{
+		md5:    md5.New(),
+		sha1:   sha1.New(),
+		sha256: sha256.New(),
+	}
+	h.writer = io.MultiWriter(h.md5, h.sha1, h.sha256)
+	return h
+}
+
+// Write menambahkan data ke semua hash.
+func (h *Hasher) Write(p []byte) (int, error) {
+	return h.writer.Write(p)
+}
+
+// Sum mengembalikan digest dari semua data yang sudah ditulis.
+func (h *Hasher) Sum() Hashes {
+	return Hashes{
+		MD5:    hex.EncodeToString(h.md5.Sum(nil)),
+		SHA1:   hex.EncodeToString(h.sha1.Sum(nil)),
+		SHA256: hex.EncodeToString(h.sha256.Sum(nil)),
+	}
+}
+
+// HashReader membaca seluruh isi reader dan mengembalikan digest-nya.
+func HashReader(r io.Reader) (Hashes, error) {
+	h := NewHasher()
+	if _, err := io.Copy(h, r); err != nil {
+		return Hashes{}, err
+	}
+	return h.Sum(), nil
+}
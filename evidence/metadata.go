@@ -0,0 +1,40 @@
+package evidence
+
+import "time"
+
+// Metadata adalah catatan chain-of-custody yang disimpan bersama setiap file bukti,
+// sehingga integritas file bisa dibuktikan dan dicocokkan dengan feed threat-intel.
+type Metadata struct {
+	Hashes
+	RequestID    string    `json:"request_id"`
+	OriginalName string    `json:"original_name"`
+	SourceField  string    `json:"source_field"`
+	Detector     string    `json:"detector"`
+	MimeType     string    `json:"mime_type"`
+	DeclaredMime string    `json:"declared_mime_type,omitempty"`
+	Encoding     string    `json:"encoding,omitempty"`
+	Mismatches   []string  `json:"mismatches,omitempty"`
+	Size         int64     `json:"size"`
+	Domain       string    `json:"domain"`
+	Path         string    `json:"path"`
+	Method       string    `json:"method"`
+	RemoteAddr   string    `json:"remote_addr"`
+	CapturedAt   time.Time `json:"captured_at"`
+
+	// Diisi untuk member yang diekstrak dari archive
+	ArchivePath  string `json:"archive_path,omitempty"`
+	ParentSHA256 string `json:"parent_sha256,omitempty"`
+
+	// Diisi jika client mengirim sertifikat mTLS yang terverifikasi
+	ClientCert *ClientCert `json:"client_cert,omitempty"`
+}
+
+// ClientCert adalah identitas client dari sertifikat mTLS yang sudah diverifikasi
+// terhadap CA bundle, untuk menunjukkan partner mana yang mengirim file bukti.
+type ClientCert struct {
+	Subject     string   `json:"subject"`
+	Issuer      string   `json:"issuer"`
+	SANs        []string `json:"sans,omitempty"` // Berformat "DNS:...", "email:...", "URI:..." atau "IP:..."
+	Serial      string   `json:"serial"`
+	Fingerprint string   `json:"fingerprint_sha256"` // SHA-256 dari sertifikat DER, hex huruf kecil
+}
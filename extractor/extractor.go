@@ -0,0 +1,222 @@
+package extractor
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/luhtaf/corator/buffer"
+	"github.com/luhtaf/corator/config"
+	"github.com/luhtaf/corator/evidence"
+)
+
+// ErrLimitExceeded dikembalikan saat ekstraksi dihentikan karena batas jumlah member
+// atau total ukuran hasil dekompresi terlampaui (misalnya zip bomb).
+var ErrLimitExceeded = errors.New("batas ekstraksi archive terlampaui")
+
+// Format container yang dikenali dari magic bytes.
+const (
+	formatNone = iota
+	formatZip
+	formatTar
+	formatGzip
+	formatBzip2
+	formatSevenZip // Dikenali tetapi tidak dibongkar
+	formatRar      // Dikenali tetapi tidak dibongkar
+)
+
+// Member adalah satu file di dalam archive beserta hash dan posisinya.
+type Member struct {
+	evidence.Hashes
+	Name         string // Nama member di dalam archive induknya
+	ArchivePath  string // Path lengkap dari archive terluar, e.g. "bundle.zip!/docs/a.tar.gz!/a.exe"
+	ParentSHA256 string // SHA-256 archive yang langsung memuat member ini
+	Depth        int    // Kedalaman nesting, 1 untuk member archive terluar
+	Size         int64
+	MimeType     string
+}
+
+// Extractor membongkar archive ZIP, TAR, GZIP dan BZIP2 secara rekursif dengan
+// batas kedalaman, jumlah member dan total ukuran hasil dekompresi. Archive 7z dan RAR
+// dikenali tetapi tidak dibongkar; archive tersebut tetap tersimpan utuh sebagai bukti.
+type Extractor struct {
+	cfg       config.ArchiveConfig
+	bufferCfg config.BufferConfig
+}
+
+// New membuat instance baru dari Extractor.
+func New(cfg config.ArchiveConfig, bufferCfg config.BufferConfig) *Extractor {
+	return &Extractor{cfg: cfg, bufferCfg: bufferCfg}
+}
+
+// Extract membongkar content jika berupa archive dan memanggil fn untuk setiap member,
+// termasuk member dari archive bersarang. name adalah nama file content dan sha256
+// adalah hash-nya. Member yang sudah dilaporkan tetap valid walaupun Extract
+// mengembalikan error, misalnya ErrLimitExceeded.
+func (e *Extractor) Extract(content *buffer.SpillBuffer, name, sha256 string, fn func(Member)) error {
+	w := &walker{
+		extractor: e,
+		fn:        fn,
+		remaining: e.cfg.MaxTotalSize,
+	}
+	return w.container(content, name, sha256, 1)
+}
+
+// walker menyimpan state satu kali ekstraksi, sehingga batas berlaku untuk
+// seluruh pohon archive, bukan per level.
+type walker struct {
+	extractor *Extractor
+	fn        func(Member)
+	members   int
+	remaining int64
+}
+
+// container membongkar content sesuai formatnya. Content yang bukan archive diabaikan.
+func (w *walker) container(content *buffer.SpillBuffer, archivePath, parentSHA256 string, depth int) error {
+	switch detectFormat(content.Head(512)) {
+	case formatZip:
+		zr, err := zip.NewReader(content.Reader(), content.Size())
+		if err != nil {
+			return fmt.Errorf("gagal membaca zip %s: %w", archivePath, err)
+		}
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				log.Printf("PERINGATAN: Gagal membuka member %s!/%s: %v", archivePath, f.Name, err)
+				continue
+			}
+			err = w.member(rc, f.Name, archivePath, parentSHA256, depth)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+
+	case formatTar:
+		tr := tar.NewReader(content.Reader())
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("gagal membaca tar %s: %w", archivePath, err)
+			}
+			if hdr.Typeflag != tar.TypeReg {
+				continue
+			}
+			if err := w.member(tr, hdr.Name, archivePath, parentSHA256, depth); err != nil {
+				return err
+			}
+		}
+
+	case formatGzip:
+		gz, err := gzip.NewReader(content.Reader())
+		if err != nil {
+			return fmt.Errorf("gagal membaca gzip %s: %w", archivePath, err)
+		}
+		defer gz.Close()
+		name := gz.Name
+		if name == "" {
+			name = decompressedName(archivePath, ".gz", ".tgz")
+		}
+		return w.member(gz, name, archivePath, parentSHA256, depth)
+
+	case formatBzip2:
+		name := decompressedName(archivePath, ".bz2", ".tbz2")
+		return w.member(bzip2.NewReader(content.Reader()), name, archivePath, parentSHA256, depth)
+
+	case formatSevenZip:
+		log.Printf("PERINGATAN: Archive 7z %s tidak didukung, member tidak diekstrak", archivePath)
+
+	case formatRar:
+		log.Printf("PERINGATAN: Archive RAR %s tidak didukung, member tidak diekstrak", archivePath)
+	}
+
+	return nil
+}
+
+// member menyalin satu member ke buffer sambil menghitung hash, melaporkannya ke fn,
+// lalu membongkarnya lagi jika member tersebut juga archive dan kedalaman masih diizinkan.
+func (w *walker) member(r io.Reader, name, archivePath, parentSHA256 string, depth int) error {
+	if w.members >= w.extractor.cfg.MaxMembers {
+		return fmt.Errorf("%w: lebih dari %d member", ErrLimitExceeded, w.extractor.cfg.MaxMembers)
+	}
+	w.members++
+
+	content := buffer.NewFromConfig(w.extractor.bufferCfg)
+	defer content.Close()
+
+	hasher := evidence.NewHasher()
+	n, err := io.Copy(io.MultiWriter(content, hasher), io.LimitReader(r, w.remaining+1))
+	if n > w.remaining {
+		return fmt.Errorf("%w: total hasil dekompresi lebih dari %d byte", ErrLimitExceeded, w.extractor.cfg.MaxTotalSize)
+	}
+	w.remaining -= n
+	memberPath := archivePath + "!/" + name
+	if err != nil {
+		// Member rusak atau terenkripsi, lewati tanpa menghentikan ekstraksi member lain
+		log.Printf("PERINGATAN: Gagal mengekstrak member %s: %v", memberPath, err)
+		return nil
+	}
+
+	hashes := hasher.Sum()
+	w.fn(Member{
+		Hashes:       hashes,
+		Name:         name,
+		ArchivePath:  memberPath,
+		ParentSHA256: parentSHA256,
+		Depth:        depth,
+		Size:         n,
+		MimeType:     http.DetectContentType(content.Head(512)),
+	})
+
+	if depth >= w.extractor.cfg.MaxDepth {
+		return nil
+	}
+	return w.container(content, memberPath, hashes.SHA256, depth+1)
+}
+
+// detectFormat mengenali format container dari magic bytes.
+func detectFormat(head []byte) int {
+	switch {
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")), bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		return formatZip
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		return formatGzip
+	case bytes.HasPrefix(head, []byte("BZh")):
+		return formatBzip2
+	case len(head) >= 262 && bytes.Equal(head[257:262], []byte("ustar")):
+		return formatTar
+	case bytes.HasPrefix(head, []byte("7z\xbc\xaf\x27\x1c")):
+		return formatSevenZip
+	case bytes.HasPrefix(head, []byte("Rar!\x1a\x07")): // RAR 4 dan RAR 5
+		return formatRar
+	}
+	return formatNone
+}
+
+// decompressedName menebak nama file hasil dekompresi dari nama archive,
+// e.g. "a.tar.gz" menjadi "a.tar" dan "a.tgz" menjadi "a.tar".
+func decompressedName(archivePath, ext, tarExt string) string {
+	name := path.Base(archivePath[strings.LastIndex(archivePath, "!/")+1:])
+	switch {
+	case strings.HasSuffix(name, tarExt):
+		return strings.TrimSuffix(name, tarExt) + ".tar"
+	case strings.HasSuffix(name, ext):
+		return strings.TrimSuffix(name, ext)
+	}
+	return name + ".out"
+}
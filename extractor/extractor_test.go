@@ -0,0 +1,206 @@
+package extractor
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/luhtaf/corator/buffer"
+	"github.com/luhtaf/corator/config"
+)
+
+func newTestExtractor(t *testing.T) *Extractor {
+	return New(config.ArchiveConfig{MaxDepth: 3, MaxMembers: 10, MaxTotalSize: 1 << 20},
+		config.BufferConfig{MemoryLimit: 1 << 20, TempDir: t.TempDir()})
+}
+
+// extract membongkar data dan mengembalikan path semua member yang dilaporkan.
+func extract(t *testing.T, e *Extractor, name string, data []byte) []string {
+	t.Helper()
+	content := buffer.New(1<<20, t.TempDir())
+	defer content.Close()
+	content.Write(data)
+
+	var paths []string
+	if err := e.Extract(content, name, "", func(m Member) { paths = append(paths, m.ArchivePath) }); err != nil {
+		t.Fatalf("Extract(%s): %v", name, err)
+	}
+	return paths
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := map[string]int{
+		"PK\x03\x04rest":             formatZip,
+		"\x1f\x8b\x08":               formatGzip,
+		"BZh91AY":                    formatBzip2,
+		"7z\xbc\xaf\x27\x1c\x00\x04": formatSevenZip,
+		"Rar!\x1a\x07\x00":           formatRar,
+		"Rar!\x1a\x07\x01\x00":       formatRar,
+		"%PDF-1.7":                   formatNone,
+	}
+	for head, want := range tests {
+		if got := detectFormat([]byte(head)); got != want {
+			t.Errorf("detectFormat(%q) = %d, seharusnya %d", head, got, want)
+		}
+	}
+}
+
+func TestUnsupportedArchivesAreSkipped(t *testing.T) {
+	e := newTestExtractor(t)
+
+	// Archive 7z di dalam zip tetap dilaporkan sebagai member, tetapi tidak dibongkar
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	f, _ := zw.Create("payload.7z")
+	f.Write([]byte("7z\xbc\xaf\x27\x1c\x00\x04isi terkompresi"))
+	zw.Close()
+
+	paths := extract(t, e, "bundle.zip", buf.Bytes())
+	if len(paths) != 1 || paths[0] != "bundle.zip!/payload.7z" {
+		t.Errorf("member = %v, seharusnya hanya bundle.zip!/payload.7z", paths)
+	}
+
+	if paths := extract(t, e, "payload.rar", []byte("Rar!\x1a\x07\x01\x00isi terkompresi")); len(paths) != 0 {
+		t.Errorf("archive RAR seharusnya tidak dibongkar, member = %v", paths)
+	}
+}
+
+// zipOf membuat archive zip dari pasangan nama dan isi file.
+func zipOf(t *testing.T, files ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i := 0; i < len(files); i += 2 {
+		f, err := zw.Create(files[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(files[i+1]))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// tarGzOf membuat archive tar.gz dari pasangan nama dan isi file, beserta tar di dalamnya.
+func tarGzOf(t *testing.T, files ...string) (tarGz, tarData []byte) {
+	t.Helper()
+	var tarBuf bytes.Buffer
+	tw := tar.NewWriter(&tarBuf)
+	for i := 0; i < len(files); i += 2 {
+		tw.WriteHeader(&tar.Header{Name: files[i], Mode: 0600, Size: int64(len(files[i+1])), Typeflag: tar.TypeReg})
+		tw.Write([]byte(files[i+1]))
+	}
+	tw.Close()
+
+	var gzBuf bytes.Buffer
+	gw := gzip.NewWriter(&gzBuf)
+	gw.Write(tarBuf.Bytes())
+	gw.Close()
+	return gzBuf.Bytes(), tarBuf.Bytes()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// extractAll membongkar data dan mengembalikan semua member beserta error Extract.
+func extractAll(t *testing.T, e *Extractor, name string, data []byte) ([]Member, error) {
+	t.Helper()
+	content := buffer.New(1<<20, t.TempDir())
+	defer content.Close()
+	content.Write(data)
+
+	var members []Member
+	err := e.Extract(content, name, sha256Hex(data), func(m Member) { members = append(members, m) })
+	return members, err
+}
+
+func TestExtractNestedZipTarGz(t *testing.T) {
+	payload := "MZ\x90\x00payload"
+	tarGz, tarData := tarGzOf(t, "bin/a.exe", payload)
+	bundle := zipOf(t, "readme.txt", "halo", "docs/a.tar.gz", string(tarGz))
+
+	members, err := extractAll(t, newTestExtractor(t), "bundle.zip", bundle)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+
+	want := []struct {
+		path   string
+		parent string
+		depth  int
+		sha256 string
+	}{
+		{"bundle.zip!/readme.txt", sha256Hex(bundle), 1, sha256Hex([]byte("halo"))},
+		{"bundle.zip!/docs/a.tar.gz", sha256Hex(bundle), 1, sha256Hex(tarGz)},
+		{"bundle.zip!/docs/a.tar.gz!/a.tar", sha256Hex(tarGz), 2, sha256Hex(tarData)},
+		{"bundle.zip!/docs/a.tar.gz!/a.tar!/bin/a.exe", sha256Hex(tarData), 3, sha256Hex([]byte(payload))},
+	}
+	if len(members) != len(want) {
+		t.Fatalf("jumlah member = %d, seharusnya %d: %+v", len(members), len(want), members)
+	}
+	for i, w := range want {
+		m := members[i]
+		if m.ArchivePath != w.path || m.ParentSHA256 != w.parent || m.Depth != w.depth || m.SHA256 != w.sha256 {
+			t.Errorf("member %d = {%s parent %s depth %d sha256 %s}, seharusnya %+v",
+				i, m.ArchivePath, m.ParentSHA256, m.Depth, m.SHA256, w)
+		}
+	}
+}
+
+func TestExtractStopsAtMaxDepth(t *testing.T) {
+	tarGz, _ := tarGzOf(t, "a.exe", "MZ")
+	bundle := zipOf(t, "a.tar.gz", string(tarGz))
+
+	e := New(config.ArchiveConfig{MaxDepth: 1, MaxMembers: 10, MaxTotalSize: 1 << 20},
+		config.BufferConfig{MemoryLimit: 1 << 20, TempDir: t.TempDir()})
+	members, err := extractAll(t, e, "bundle.zip", bundle)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if len(members) != 1 || members[0].ArchivePath != "bundle.zip!/a.tar.gz" {
+		t.Errorf("member = %+v, seharusnya hanya bundle.zip!/a.tar.gz tanpa dibongkar", members)
+	}
+}
+
+func TestExtractLimits(t *testing.T) {
+	var many []string
+	for i := 0; i < 11; i++ {
+		many = append(many, fmt.Sprintf("f%02d.txt", i), "isi")
+	}
+	// Zip bomb kecil: 2 MiB nol yang terkompresi menjadi beberapa KiB
+	bomb := zipOf(t, "zeros.bin", strings.Repeat("\x00", 2<<20))
+
+	tests := []struct {
+		name        string
+		data        []byte
+		wantMembers int
+	}{
+		{"MaxMembers", zipOf(t, many...), 10},
+		{"MaxTotalSize", bomb, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.data) > 1<<20 {
+				t.Fatalf("archive uji terlalu besar: %d byte", len(tt.data))
+			}
+			members, err := extractAll(t, newTestExtractor(t), "upload.zip", tt.data)
+			if !errors.Is(err, ErrLimitExceeded) {
+				t.Errorf("error = %v, seharusnya ErrLimitExceeded", err)
+			}
+			if len(members) != tt.wantMembers {
+				t.Errorf("jumlah member yang dilaporkan = %d, seharusnya %d", len(members), tt.wantMembers)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"net/http"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/luhtaf/corator/config"
+)
+
+// defaultHTMLBlockPage adalah template HTML bawaan untuk halaman blokir.
+const defaultHTMLBlockPage = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Status}} {{.StatusText}}</title></head>
+<body>
+<h1>Request diblokir</h1>
+<p>Request Anda diblokir oleh Web Application Firewall.</p>
+<p>Jika Anda merasa ini adalah kesalahan, hubungi support dan sertakan Request ID berikut:</p>
+<pre>{{.RequestID}}</pre>
+</body>
+</html>
+`
+
+// defaultJSONBlockPage adalah template JSON bawaan untuk halaman blokir.
+const defaultJSONBlockPage = `{"error":"request diblokir oleh WAF","status":{{.Status}},"request_id":"{{.RequestID}}"}
+`
+
+// defaultHTMLErrorPage adalah template HTML bawaan untuk halaman error 502/504 saat
+// backend gagal atau terlalu lama merespons.
+const defaultHTMLErrorPage = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Status}} {{.StatusText}}</title></head>
+<body>
+<h1>{{if eq .Status 504}}Backend tidak merespons{{else}}Backend tidak dapat dijangkau{{end}}</h1>
+<p>Layanan sedang tidak tersedia. Silakan coba beberapa saat lagi.</p>
+<p>Jika masalah berlanjut, hubungi support dan sertakan Request ID berikut:</p>
+<pre>{{.RequestID}}</pre>
+</body>
+</html>
+`
+
+// defaultJSONErrorPage adalah template JSON bawaan untuk halaman error 502/504.
+const defaultJSONErrorPage = `{"error":"{{if eq .Status 504}}backend tidak merespons{{else}}backend tidak dapat dijangkau{{end}}","status":{{.Status}},"request_id":"{{.RequestID}}"}
+`
+
+// BlockPageData adalah data yang tersedia di dalam template halaman blokir.
+type BlockPageData struct {
+	RequestID  string
+	Status     int
+	StatusText string
+	RuleID     int
+	Timestamp  time.Time
+}
+
+// BlockPage merender halaman blokir dalam format HTML atau JSON sesuai header Accept.
+// Tipe yang sama dipakai untuk halaman error proxy (lihat NewErrorPage).
+type BlockPage struct {
+	name     string // Nama halaman di pesan error, misal "halaman blokir"
+	fallback string // Teks polos jika template gagal dirender
+	html     *htmltemplate.Template
+	json     *texttemplate.Template
+}
+
+// NewBlockPage membuat BlockPage dari file template yang dikonfigurasi,
+// atau menggunakan template bawaan jika path kosong.
+func NewBlockPage(cfg config.BlockPageConfig) (*BlockPage, error) {
+	return newPage(cfg, "halaman blokir", "Request diblokir oleh WAF", defaultHTMLBlockPage, defaultJSONBlockPage)
+}
+
+// NewErrorPage membuat halaman error 502/504 yang ditampilkan saat backend gagal atau
+// timeout, dengan data template yang sama seperti halaman blokir.
+func NewErrorPage(cfg config.BlockPageConfig) (*BlockPage, error) {
+	return newPage(cfg, "halaman error", "Backend tidak tersedia", defaultHTMLErrorPage, defaultJSONErrorPage)
+}
+
+// newPage memuat template dari file yang dikonfigurasi atau memakai template bawaan.
+func newPage(cfg config.BlockPageConfig, name, fallback, defaultHTML, defaultJSON string) (*BlockPage, error) {
+	page := &BlockPage{
+		name:     name,
+		fallback: fallback,
+		html:     htmltemplate.Must(htmltemplate.New("html").Parse(defaultHTML)),
+		json:     texttemplate.Must(texttemplate.New("json").Parse(defaultJSON)),
+	}
+
+	if cfg.HTMLTemplatePath != "" {
+		tmpl, err := htmltemplate.ParseFiles(cfg.HTMLTemplatePath)
+		if err != nil {
+			return nil, fmt.Errorf("gagal memuat template HTML %s: %w", name, err)
+		}
+		page.html = tmpl
+	}
+
+	if cfg.JSONTemplatePath != "" {
+		tmpl, err := texttemplate.ParseFiles(cfg.JSONTemplatePath)
+		if err != nil {
+			return nil, fmt.Errorf("gagal memuat template JSON %s: %w", name, err)
+		}
+		page.json = tmpl
+	}
+
+	return page, nil
+}
+
+// Write merender halaman ke response dengan status yang diberikan.
+func (p *BlockPage) Write(w http.ResponseWriter, req *http.Request, data BlockPageData) {
+	var (
+		buf         bytes.Buffer
+		err         error
+		contentType string
+	)
+
+	if prefersJSON(req.Header.Get("Accept")) {
+		contentType = "application/json; charset=utf-8"
+		err = p.json.Execute(&buf, data)
+	} else {
+		contentType = "text/html; charset=utf-8"
+		err = p.html.Execute(&buf, data)
+	}
+
+	if err != nil {
+		log.Printf("[%s] Gagal merender %s: %v", data.RequestID, p.name, err)
+		buf.Reset()
+		contentType = "text/plain; charset=utf-8"
+		fmt.Fprintf(&buf, "%s (Request ID: %s)\n", p.fallback, data.RequestID)
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(data.Status)
+	w.Write(buf.Bytes())
+}
+
+// prefersJSON mengembalikan true jika client meminta JSON dan tidak meminta HTML.
+func prefersJSON(accept string) bool {
+	accept = strings.ToLower(accept)
+	return strings.Contains(accept, "json") && !strings.Contains(accept, "text/html")
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luhtaf/corator/config"
+)
+
+func TestBlockPageFormatFromAccept(t *testing.T) {
+	page := newTestBlockPage(t)
+
+	tests := []struct {
+		accept   string
+		wantJSON bool
+	}{
+		{"", false},
+		{"*/*", false},
+		{"application/json", true},
+		{"application/problem+json", true},
+		{"text/html,application/xhtml+xml,application/json;q=0.9", false},
+		{"TEXT/HTML", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept", tt.accept)
+		rec := httptest.NewRecorder()
+		page.Write(rec, req, BlockPageData{RequestID: "req-123", Status: http.StatusForbidden, Timestamp: time.Now()})
+
+		contentType := rec.Header().Get("Content-Type")
+		if got := strings.HasPrefix(contentType, "application/json"); got != tt.wantJSON {
+			t.Errorf("Accept %q menghasilkan Content-Type %q", tt.accept, contentType)
+			continue
+		}
+		if tt.wantJSON {
+			var body struct {
+				Status    int    `json:"status"`
+				RequestID string `json:"request_id"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Errorf("body JSON tidak valid: %v (%q)", err, rec.Body.String())
+			} else if body.Status != http.StatusForbidden || body.RequestID != "req-123" {
+				t.Errorf("body JSON = %+v", body)
+			}
+		} else if !strings.Contains(rec.Body.String(), "req-123") {
+			t.Errorf("halaman HTML tidak memuat request ID: %q", rec.Body.String())
+		}
+	}
+}
+
+func TestBlockPageCustomTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "block.html")
+	if err := os.WriteFile(path, []byte(`<p>Ditolak rule {{.RuleID}}, ID {{.RequestID}}</p>`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	page, err := NewBlockPage(config.BlockPageConfig{HTMLTemplatePath: path})
+	if err != nil {
+		t.Fatalf("NewBlockPage: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	page.Write(rec, httptest.NewRequest("GET", "/", nil), BlockPageData{RequestID: "<req>", Status: 403, RuleID: 942100})
+
+	if got := rec.Body.String(); got != "<p>Ditolak rule 942100, ID &lt;req&gt;</p>" {
+		t.Errorf("body = %q", got)
+	}
+}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+
+	"github.com/luhtaf/corator/evidence"
+)
+
+// clientCertFrom mengambil identitas client dari sertifikat mTLS. Hanya sertifikat yang
+// lolos verifikasi terhadap CA bundle listener yang dipakai; nil jika tidak ada.
+func clientCertFrom(cs *tls.ConnectionState) *evidence.ClientCert {
+	if cs == nil || len(cs.VerifiedChains) == 0 || len(cs.PeerCertificates) == 0 {
+		return nil
+	}
+	leaf := cs.PeerCertificates[0]
+
+	var sans []string
+	for _, name := range leaf.DNSNames {
+		sans = append(sans, "DNS:"+name)
+	}
+	for _, email := range leaf.EmailAddresses {
+		sans = append(sans, "email:"+email)
+	}
+	for _, uri := range leaf.URIs {
+		sans = append(sans, "URI:"+uri.String())
+	}
+	for _, ip := range leaf.IPAddresses {
+		sans = append(sans, "IP:"+ip.String())
+	}
+
+	fingerprint := sha256.Sum256(leaf.Raw)
+	return &evidence.ClientCert{
+		Subject:     leaf.Subject.String(),
+		Issuer:      leaf.Issuer.String(),
+		SANs:        sans,
+		Serial:      leaf.SerialNumber.Text(16),
+		Fingerprint: hex.EncodeToString(fingerprint[:]),
+	}
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"time"
+
+	"github.com/luhtaf/corator/detector"
+	"github.com/luhtaf/corator/evidence"
+	"github.com/luhtaf/corator/extractor"
+	"github.com/luhtaf/corator/logger"
+	"github.com/luhtaf/corator/metrics"
+	"github.com/luhtaf/corator/spool"
+	"github.com/luhtaf/corator/uploader"
+)
+
+// errQueueFull adalah alasan yang dicatat di spool untuk job yang di-spill karena antrean penuh.
+var errQueueFull = errors.New("antrean worker penuh")
+
+// evidenceJob memproses satu file hasil deteksi: hashing, upload, logging, lalu
+// ekstraksi archive jika extractor aktif.
+type evidenceJob struct {
+	result    detector.DetectionResult
+	meta      evidence.Metadata
+	uploader  uploader.Uploader
+	spool     *spool.Spool
+	extractor *extractor.Extractor // nil jika ekstraksi archive dinonaktifkan
+}
+
+// uniqueFilename mengembalikan nama file bukti di storage.
+func (j *evidenceJob) uniqueFilename() string {
+	return fmt.Sprintf("%s_%s", j.meta.RequestID, j.result.FileName)
+}
+
+// hash melengkapi metadata dengan digest file, kecuali jika sudah dihitung sebelumnya.
+func (j *evidenceJob) hash() error {
+	if j.meta.SHA256 != "" {
+		return nil
+	}
+	hashes, err := evidence.HashReader(j.result.Content.Reader())
+	if err != nil {
+		return fmt.Errorf("gagal menghitung hash file %s: %w", j.result.FileName, err)
+	}
+	j.meta.Hashes = hashes
+	return nil
+}
+
+// Run menjalankan hashing, upload dan logging untuk file bukti.
+func (j *evidenceJob) Run(ctx context.Context) {
+	defer j.result.Close()
+	requestID := j.meta.RequestID
+
+	// Hitung hash sebelum upload agar bisa ikut disimpan sebagai metadata file
+	if err := j.hash(); err != nil {
+		log.Printf("[%s] %v", requestID, err)
+		return
+	}
+
+	// Upload file, simpan ke spool untuk dicoba lagi jika gagal
+	started := time.Now()
+	uploadPath, err := j.uploader.Upload(ctx, j.result.Content.Reader(), j.uniqueFilename(), j.meta)
+	metrics.ObserveUpload(j.uploader.Name(), started, err)
+	if err != nil {
+		log.Printf("[%s] Gagal upload file %s, disimpan ke spool: %v", requestID, j.result.FileName, err)
+		if err := j.spool.EnqueueUpload(j.result.Content.Reader(), j.uploader.Name(), j.uniqueFilename(), j.meta, err); err != nil {
+			log.Printf("[%s] PERINGATAN: Gagal menyimpan file %s ke spool, bukti hilang: %v", requestID, j.result.FileName, err)
+		}
+	} else {
+		// Kirim event log ke semua logger aktif
+		j.spool.Deliver(ctx, logger.NewLogEvent(j.meta, uploadPath))
+		log.Printf("[%s] File terdeteksi dan diunggah: %s dari field %s (sha256 %s)", requestID, uploadPath, j.result.SourceField, j.meta.SHA256)
+	}
+
+	j.extractMembers(ctx, uploadPath)
+}
+
+// extractMembers membongkar file bukti jika berupa archive dan mencatat setiap member
+// sebagai LogEvent anak. Member tidak diunggah terpisah karena sudah tersimpan di dalam
+// archive induknya; upload path event anak menunjuk ke archive tersebut (kosong jika
+// archive masih menunggu di spool).
+func (j *evidenceJob) extractMembers(ctx context.Context, uploadPath string) {
+	if j.extractor == nil {
+		return
+	}
+
+	count := 0
+	err := j.extractor.Extract(j.result.Content, j.result.FileName, j.meta.SHA256, func(m extractor.Member) {
+		meta := j.meta
+		meta.Hashes = m.Hashes
+		meta.OriginalName = path.Base(m.Name)
+		meta.MimeType = m.MimeType
+		meta.DeclaredMime = ""
+		meta.Encoding = ""
+		meta.Mismatches = nil
+		meta.Size = m.Size
+		meta.ArchivePath = m.ArchivePath
+		meta.ParentSHA256 = m.ParentSHA256
+
+		j.spool.Deliver(ctx, logger.NewLogEvent(meta, uploadPath))
+		count++
+	})
+	if err != nil {
+		log.Printf("[%s] PERINGATAN: Ekstraksi archive %s tidak lengkap: %v", j.meta.RequestID, j.result.FileName, err)
+	}
+	if count > 0 {
+		log.Printf("[%s] %d member diekstrak dari archive %s", j.meta.RequestID, count, j.result.FileName)
+	}
+}
+
+// Spill menyimpan file bukti ke spool agar diunggah nanti oleh proses retry.
+func (j *evidenceJob) Spill() error {
+	defer j.result.Close()
+
+	if err := j.hash(); err != nil {
+		return err
+	}
+	return j.spool.EnqueueUpload(j.result.Content.Reader(), j.uploader.Name(), j.uniqueFilename(), j.meta, errQueueFull)
+}
+
+// Discard membuang file bukti yang tidak akan diproses.
+func (j *evidenceJob) Discard() {
+	log.Printf("[%s] PERINGATAN: Antrean worker penuh, file %s dari field %s dibuang", j.meta.RequestID, j.result.FileName, j.result.SourceField)
+	j.result.Close()
+}
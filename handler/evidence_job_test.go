@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/luhtaf/corator/buffer"
+	"github.com/luhtaf/corator/config"
+	"github.com/luhtaf/corator/detector"
+	"github.com/luhtaf/corator/evidence"
+	"github.com/luhtaf/corator/extractor"
+	"github.com/luhtaf/corator/logger"
+	"github.com/luhtaf/corator/spool"
+	"github.com/luhtaf/corator/uploader"
+)
+
+// memoryLogger menyimpan event yang dicatat di memori.
+type memoryLogger struct {
+	events []logger.LogEvent
+}
+
+func (l *memoryLogger) Name() string                    { return "memory" }
+func (l *memoryLogger) Check(ctx context.Context) error { return nil }
+func (l *memoryLogger) Close() error                    { return nil }
+func (l *memoryLogger) Log(ctx context.Context, event logger.LogEvent) error {
+	l.events = append(l.events, event)
+	return nil
+}
+
+// newTestJob membuat evidenceJob untuk data yang diunggah ke direktori lokal dan
+// dicatat oleh memoryLogger.
+func newTestJob(t *testing.T, fileName string, data []byte, ext *extractor.Extractor) (*evidenceJob, *memoryLogger, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	up, err := uploader.NewLocalUploader(config.LocalConfig{Path: dir})
+	if err != nil {
+		t.Fatalf("NewLocalUploader: %v", err)
+	}
+	mem := &memoryLogger{}
+	sp, err := spool.New(config.SpoolConfig{Path: t.TempDir(), RetryInterval: time.Minute, MinBackoff: time.Second, MaxBackoff: time.Minute}, []uploader.Uploader{up}, []logger.Logger{mem})
+	if err != nil {
+		t.Fatalf("spool.New: %v", err)
+	}
+
+	content := buffer.New(1<<20, t.TempDir())
+	content.Write(data)
+	return &evidenceJob{
+		result: detector.DetectionResult{Content: content, FileName: fileName, SourceField: "multipart-field:file"},
+		meta: evidence.Metadata{
+			RequestID:    "req-parent",
+			OriginalName: fileName,
+			Size:         int64(len(data)),
+			CapturedAt:   time.Now(),
+		},
+		uploader:  up,
+		spool:     sp,
+		extractor: ext,
+	}, mem, dir
+}
+
+func TestArchiveMembersLoggedAsChildEvents(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range []string{"a.exe", "docs/b.pdf"} {
+		f, _ := zw.Create(name)
+		f.Write([]byte("isi " + name))
+	}
+	zw.Close()
+
+	ext := extractor.New(config.ArchiveConfig{MaxDepth: 3, MaxMembers: 10, MaxTotalSize: 1 << 20},
+		config.BufferConfig{MemoryLimit: 1 << 20, TempDir: t.TempDir()})
+	job, mem, _ := newTestJob(t, "bundle.zip", buf.Bytes(), ext)
+	job.Run(context.Background())
+
+	if len(mem.events) != 3 {
+		t.Fatalf("jumlah event = %d, seharusnya 1 induk dan 2 anak", len(mem.events))
+	}
+	parent := mem.events[0]
+	if parent.ArchivePath != "" || parent.SHA256 == "" {
+		t.Fatalf("event pertama bukan event archive induk: %+v", parent)
+	}
+	for _, child := range mem.events[1:] {
+		if child.RequestID != parent.RequestID {
+			t.Errorf("event anak %s memakai request ID %q, seharusnya %q", child.ArchivePath, child.RequestID, parent.RequestID)
+		}
+		if child.ParentSHA256 != parent.SHA256 {
+			t.Errorf("event anak %s tidak menunjuk hash archive induk", child.ArchivePath)
+		}
+		if child.UploadPath != parent.UploadPath {
+			t.Errorf("upload path event anak %s = %q, seharusnya archive induk %q", child.ArchivePath, child.UploadPath, parent.UploadPath)
+		}
+	}
+	if got := mem.events[2].ArchivePath; got != "bundle.zip!/docs/b.pdf" {
+		t.Errorf("archive path = %q, seharusnya bundle.zip!/docs/b.pdf", got)
+	}
+	if got := mem.events[2].FileName; got != "b.pdf" {
+		t.Errorf("nama file event anak = %q, seharusnya b.pdf", got)
+	}
+}
+
+func TestEvidenceHashesMatchStoredBlob(t *testing.T) {
+	data := bytes.Repeat([]byte("bukti chain-of-custody\n"), 1000)
+	job, mem, _ := newTestJob(t, "laporan.txt", data, nil)
+	job.Run(context.Background())
+
+	if len(mem.events) != 1 {
+		t.Fatalf("jumlah event = %d, seharusnya 1", len(mem.events))
+	}
+	event := mem.events[0]
+
+	stored, err := os.ReadFile(event.UploadPath)
+	if err != nil {
+		t.Fatalf("gagal membaca file bukti: %v", err)
+	}
+	if !bytes.Equal(stored, data) {
+		t.Fatalf("isi file bukti berbeda dari data yang diunggah")
+	}
+	md5Sum, sha1Sum, sha256Sum := md5.Sum(stored), sha1.Sum(stored), sha256.Sum256(stored)
+	want := evidence.Hashes{
+		MD5:    hex.EncodeToString(md5Sum[:]),
+		SHA1:   hex.EncodeToString(sha1Sum[:]),
+		SHA256: hex.EncodeToString(sha256Sum[:]),
+	}
+
+	if got := (evidence.Hashes{MD5: event.MD5, SHA1: event.SHA1, SHA256: event.SHA256}); got != want {
+		t.Errorf("hash di event log = %+v, seharusnya %+v", got, want)
+	}
+
+	raw, err := os.ReadFile(event.UploadPath + ".json")
+	if err != nil {
+		t.Fatalf("gagal membaca sidecar: %v", err)
+	}
+	var sidecar evidence.Metadata
+	if err := json.Unmarshal(raw, &sidecar); err != nil {
+		t.Fatalf("sidecar bukan JSON yang valid: %v", err)
+	}
+	if sidecar.Hashes != want {
+		t.Errorf("hash di sidecar = %+v, seharusnya %+v", sidecar.Hashes, want)
+	}
+	if sidecar.Size != int64(len(stored)) || sidecar.RequestID != "req-parent" || sidecar.OriginalName != "laporan.txt" {
+		t.Errorf("sidecar = %+v", sidecar)
+	}
+}
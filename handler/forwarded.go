@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ParseTrustedProxies mengubah daftar IP atau CIDR menjadi prefix. Alamat tunggal
+// diperlakukan sebagai prefix /32 (IPv4) atau /128 (IPv6).
+func ParseTrustedProxies(entries []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, entry := range entries {
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q bukan IP atau CIDR yang valid", entry)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// setForwardedHeaders mengisi X-Forwarded-For, X-Forwarded-Host, X-Forwarded-Proto dan
+// Forwarded (RFC 7239) pada request ke backend. Nilai dari client hanya diteruskan jika
+// koneksi datang dari trusted proxy; selain itu header dari client dibuang agar backend
+// tidak tertipu alamat palsu.
+func setForwardedHeaders(out, in *http.Request, trusted []netip.Prefix) {
+	clientIP := remoteIP(in.RemoteAddr)
+	proto := "http"
+	if in.TLS != nil {
+		proto = "https"
+	}
+
+	xff, xfHost, xfProto := clientIP, in.Host, proto
+	var forwarded []string
+	if isTrusted(clientIP, trusted) {
+		if prior := in.Header.Values("X-Forwarded-For"); len(prior) > 0 {
+			xff = strings.Join(prior, ", ") + ", " + clientIP
+		}
+		if h := in.Header.Get("X-Forwarded-Host"); h != "" {
+			xfHost = h
+		}
+		if p := in.Header.Get("X-Forwarded-Proto"); p != "" {
+			xfProto = p
+		}
+		forwarded = in.Header.Values("Forwarded")
+	}
+
+	out.Header.Set("X-Forwarded-For", xff)
+	out.Header.Set("X-Forwarded-Host", xfHost)
+	out.Header.Set("X-Forwarded-Proto", xfProto)
+
+	element := fmt.Sprintf("for=%s;host=%s;proto=%s", forwardedNode(clientIP), forwardedValue(in.Host), proto)
+	out.Header.Set("Forwarded", strings.Join(append(forwarded, element), ", "))
+}
+
+// remoteIP mengambil IP dari RemoteAddr tanpa port.
+func remoteIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+// isTrusted mengembalikan true jika IP termasuk salah satu prefix trusted proxy.
+func isTrusted(ip string, trusted []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedNode memformat IP untuk parameter "for" Forwarded; IPv6 wajib dikurung
+// dan di-quote sesuai RFC 7239.
+func forwardedNode(ip string) string {
+	if strings.Contains(ip, ":") {
+		return `"[` + ip + `]"`
+	}
+	return ip
+}
+
+// forwardedValue meng-quote nilai yang mengandung karakter di luar token RFC 7230,
+// misalnya host dengan port.
+func forwardedValue(v string) string {
+	if v != "" && strings.IndexFunc(v, func(r rune) bool { return !isTokenChar(r) }) < 0 {
+		return v
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v) + `"`
+}
+
+func isTokenChar(r rune) bool {
+	return r < 0x80 && (r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' ||
+		strings.ContainsRune("!#$%&'*+-.^_`|~", r))
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"crypto/tls"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSetForwardedHeaders(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8", "2001:db8::1"})
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		tls        bool
+		headers    map[string]string
+		want       map[string]string
+	}{
+		{
+			name:       "client langsung tanpa header",
+			remoteAddr: "203.0.113.7:5000",
+			want: map[string]string{
+				"X-Forwarded-For":   "203.0.113.7",
+				"X-Forwarded-Host":  "shop.corator.test",
+				"X-Forwarded-Proto": "http",
+				"Forwarded":         "for=203.0.113.7;host=shop.corator.test;proto=http",
+			},
+		},
+		{
+			name:       "header palsu dari peer tidak tepercaya ditimpa",
+			remoteAddr: "203.0.113.7:5000",
+			tls:        true,
+			headers: map[string]string{
+				"X-Forwarded-For":   "127.0.0.1",
+				"X-Forwarded-Host":  "admin.internal",
+				"X-Forwarded-Proto": "http",
+				"Forwarded":         "for=127.0.0.1",
+			},
+			want: map[string]string{
+				"X-Forwarded-For":   "203.0.113.7",
+				"X-Forwarded-Host":  "shop.corator.test",
+				"X-Forwarded-Proto": "https",
+				"Forwarded":         "for=203.0.113.7;host=shop.corator.test;proto=https",
+			},
+		},
+		{
+			name:       "header dari trusted proxy ditambahkan",
+			remoteAddr: "10.1.2.3:5000",
+			headers: map[string]string{
+				"X-Forwarded-For":   "198.51.100.9",
+				"X-Forwarded-Host":  "www.corator.test",
+				"X-Forwarded-Proto": "https",
+				"Forwarded":         "for=198.51.100.9;proto=https",
+			},
+			want: map[string]string{
+				"X-Forwarded-For":   "198.51.100.9, 10.1.2.3",
+				"X-Forwarded-Host":  "www.corator.test",
+				"X-Forwarded-Proto": "https",
+				"Forwarded":         "for=198.51.100.9;proto=https, for=10.1.2.3;host=shop.corator.test;proto=http",
+			},
+		},
+		{
+			name:       "trusted proxy IPv6",
+			remoteAddr: "[2001:db8::1]:5000",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.9"},
+			want: map[string]string{
+				"X-Forwarded-For": "198.51.100.9, 2001:db8::1",
+				"Forwarded":       `for="[2001:db8::1]";host=shop.corator.test;proto=http`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := httptest.NewRequest("GET", "http://shop.corator.test/", nil)
+			in.RemoteAddr = tt.remoteAddr
+			if tt.tls {
+				in.TLS = &tls.ConnectionState{}
+			}
+			for k, v := range tt.headers {
+				in.Header.Set(k, v)
+			}
+			out := in.Clone(in.Context())
+
+			setForwardedHeaders(out, in, trusted)
+			for k, want := range tt.want {
+				if got := out.Header.Get(k); got != want {
+					t.Errorf("%s = %q, seharusnya %q", k, got, want)
+				}
+			}
+		})
+	}
+}
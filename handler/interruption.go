@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/corazawaf/coraza/v3/types"
+)
+
+// handleInterruption menulis response sesuai aksi disruptive dari rule Coraza:
+// "drop" memutus koneksi, "redirect" mengarahkan client ke URL tujuan, dan
+// "deny" (atau aksi lain) menampilkan halaman blokir dengan status dari rule.
+func handleInterruption(w http.ResponseWriter, req *http.Request, blockPage *BlockPage, requestID string, it *types.Interruption) {
+	switch it.Action {
+	case "drop":
+		dropConnection(w)
+	case "redirect":
+		status := it.Status
+		if status < 300 || status > 399 {
+			status = http.StatusFound
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		http.Redirect(w, req, it.Data, status)
+	default:
+		status := interruptionStatus(it)
+		blockPage.Write(w, req, BlockPageData{
+			RequestID:  requestID,
+			Status:     status,
+			StatusText: http.StatusText(status),
+			RuleID:     it.RuleID,
+			Timestamp:  time.Now(),
+		})
+	}
+}
+
+// dropConnection menutup koneksi client tanpa mengirim response. Untuk koneksi
+// yang tidak bisa di-hijack (misal: HTTP/2), stream dibatalkan lewat http.ErrAbortHandler.
+func dropConnection(w http.ResponseWriter) {
+	conn, _, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	conn.Close()
+}
+
+// interruptionStatus menentukan status HTTP dari interupsi Coraza.
+func interruptionStatus(it *types.Interruption) int {
+	if it.Status != 0 {
+		return it.Status
+	}
+	return http.StatusForbidden
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/corazawaf/coraza/v3/types"
+	"github.com/luhtaf/corator/config"
+)
+
+func newTestBlockPage(t *testing.T) *BlockPage {
+	t.Helper()
+	page, err := NewBlockPage(config.BlockPageConfig{})
+	if err != nil {
+		t.Fatalf("NewBlockPage: %v", err)
+	}
+	return page
+}
+
+func TestHandleInterruptionDenyUsesRuleStatus(t *testing.T) {
+	page := newTestBlockPage(t)
+
+	tests := []struct {
+		name string
+		it   types.Interruption
+		want int
+	}{
+		{"status dari rule", types.Interruption{RuleID: 1001, Action: "deny", Status: 406}, 406},
+		{"tanpa status", types.Interruption{RuleID: 1002, Action: "deny"}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			rec := httptest.NewRecorder()
+			handleInterruption(rec, req, page, "req-123", &tt.it)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, seharusnya %d", rec.Code, tt.want)
+			}
+			if !strings.Contains(rec.Body.String(), "req-123") {
+				t.Errorf("halaman blokir tidak memuat request ID: %q", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleInterruptionRedirect(t *testing.T) {
+	page := newTestBlockPage(t)
+
+	tests := []struct {
+		name   string
+		status int
+		want   int
+	}{
+		{"status dari rule", http.StatusSeeOther, http.StatusSeeOther},
+		{"status bukan 3xx", http.StatusForbidden, http.StatusFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/admin", nil)
+			rec := httptest.NewRecorder()
+			handleInterruption(rec, req, page, "req-123", &types.Interruption{
+				Action: "redirect", Status: tt.status, Data: "https://corator.test/blocked",
+			})
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, seharusnya %d", rec.Code, tt.want)
+			}
+			if got := rec.Header().Get("Location"); got != "https://corator.test/blocked" {
+				t.Errorf("Location = %q, seharusnya https://corator.test/blocked", got)
+			}
+		})
+	}
+}
+
+func TestHandleInterruptionDropClosesConnection(t *testing.T) {
+	page := newTestBlockPage(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleInterruption(w, r, page, "req-123", &types.Interruption{Action: "drop"})
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatalf("client menerima response %d, seharusnya koneksi diputus", resp.StatusCode)
+	}
+}
+
+func TestHandleInterruptionDropWithoutHijackAborts(t *testing.T) {
+	page := newTestBlockPage(t)
+
+	// ResponseRecorder tidak bisa di-hijack, seperti stream HTTP/2
+	defer func() {
+		if r := recover(); r != http.ErrAbortHandler {
+			t.Errorf("recover() = %v, seharusnya http.ErrAbortHandler", r)
+		}
+	}()
+	handleInterruption(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), page, "req-123", &types.Interruption{Action: "drop"})
+}
@@ -1,89 +1,256 @@
 package handler
 
 import (
-	"bytes"
-	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"net/http/httputil"
-	"net/url"
+	"net/netip"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/corazawaf/coraza/v3"
+	"github.com/corazawaf/coraza/v3/types"
+	"github.com/gabriel-vasile/mimetype"
 	"github.com/google/uuid"
+	"github.com/luhtaf/corator/buffer"
+	"github.com/luhtaf/corator/config"
 	"github.com/luhtaf/corator/detector"
-	"github.com/luhtaf/corator/logger"
+	"github.com/luhtaf/corator/evidence"
+	"github.com/luhtaf/corator/extractor"
+	"github.com/luhtaf/corator/metrics"
+	"github.com/luhtaf/corator/policy"
+	"github.com/luhtaf/corator/spool"
 	"github.com/luhtaf/corator/uploader"
+	"github.com/luhtaf/corator/upstream"
+	"github.com/luhtaf/corator/worker"
 )
 
-// RequestHandler adalah middleware utama yang mengatur alur request.
-type RequestHandler struct {
+// Components adalah komponen yang dibangun dari konfigurasi dan bisa diganti saat
+// reload tanpa restart. Satu request memakai satu Components dari awal hingga selesai.
+type Components struct {
 	WAF       coraza.WAF
 	Detectors []detector.Detector
 	Uploader  uploader.Uploader
-	Loggers   []logger.Logger
-	Backend   *url.URL
+	Extractor *extractor.Extractor // nil jika ekstraksi archive dinonaktifkan
+	Policy    *policy.Policy
+	BlockPage *BlockPage
+	BufferCfg config.BufferConfig
+	Upstream  *upstream.Pool // Backend default untuk request yang route-nya tidak menentukan upstream
+
+	// ErrorPage ditampilkan saat backend tidak dapat dijangkau (502) atau tidak merespons (504).
+	ErrorPage *BlockPage
+	// TrustedProxies adalah alamat proxy di depan corator yang header X-Forwarded-* dan
+	// Forwarded-nya diteruskan ke backend.
+	TrustedProxies []netip.Prefix
+
+	// ExportDetections menyalin hasil deteksi ke variabel TX Coraza sebelum rule dievaluasi.
+	ExportDetections bool
+}
+
+// RequestHandler adalah middleware utama yang mengatur alur request.
+type RequestHandler struct {
+	Spool   *spool.Spool
+	Workers *worker.Pool
+
+	components atomic.Pointer[Components]
+	proxy      *httputil.ReverseProxy
 }
 
 // NewRequestHandler membuat instance baru dari RequestHandler.
-func NewRequestHandler(waf coraza.WAF, detectors []detector.Detector, up uploader.Uploader, logs []logger.Logger, backendURL *url.URL) *RequestHandler {
-	return &RequestHandler{
-		WAF:       waf,
-		Detectors: detectors,
-		Uploader:  up,
-		Loggers:   logs,
-		Backend:   backendURL,
+func NewRequestHandler(c *Components, sp *spool.Spool, workers *worker.Pool) *RequestHandler {
+	rh := &RequestHandler{
+		Spool:   sp,
+		Workers: workers,
+	}
+	rh.proxy = &httputil.ReverseProxy{
+		Rewrite:        rh.rewrite,
+		Transport:      poolTransport{},
+		ModifyResponse: rh.modifyResponse,
+		ErrorHandler:   rh.proxyErrorHandler,
 	}
+	rh.components.Store(c)
+	return rh
+}
+
+// Components mengembalikan komponen yang sedang aktif.
+func (rh *RequestHandler) Components() *Components {
+	return rh.components.Load()
+}
+
+// Swap mengganti komponen secara atomik. Request yang sedang berjalan tetap
+// diselesaikan dengan komponen lama, request baru memakai komponen baru.
+func (rh *RequestHandler) Swap(c *Components) {
+	rh.components.Store(c)
 }
 
 // ServeHTTP adalah metode yang membuat RequestHandler menjadi http.Handler.
 func (rh *RequestHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	// 1. Generate Request ID unik
 	requestID := uuid.New().String()
+	c := rh.components.Load()
 
-	// 2. Clone body request agar bisa dibaca berkali-kali
-	bodyBytes, _ := io.ReadAll(req.Body)
-	req.Body.Close() // Tutup body asli
+	// 2. Pilih route, dan tolak client tanpa sertifikat mTLS sebelum body dibaca jika route mewajibkannya
+	route := c.Policy.Match(req)
+	clientCert := clientCertFrom(req.TLS)
+	if route.RequiresClientCert() && clientCert == nil {
+		log.Printf("[%s] Request ditolak: route %s membutuhkan sertifikat client yang terverifikasi", requestID, route.Name)
+		c.BlockPage.Write(w, req, BlockPageData{
+			RequestID:  requestID,
+			Status:     http.StatusForbidden,
+			StatusText: http.StatusText(http.StatusForbidden),
+			Timestamp:  time.Now(),
+		})
+		return
+	}
 
-	// Buat reader baru untuk aplikasi kita (detector, WAF)
-	req.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+	// 3. Salin body request sekali ke buffer (spill ke disk jika besar)
+	// agar bisa dibaca berkali-kali tanpa menahan seluruhnya di memori.
+	// Body di atas BUFFER_MAX_BODY_SIZE ditolak agar tidak memenuhi disk.
+	body := buffer.NewFromConfig(c.BufferCfg)
+	defer body.Close()
+	_, err := io.Copy(body, http.MaxBytesReader(w, req.Body, c.BufferCfg.MaxBodySize))
+	req.Body.Close() // Tutup body asli
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		log.Printf("[%s] Request ditolak: body melebihi batas %d byte", requestID, maxErr.Limit)
+		c.BlockPage.Write(w, req, BlockPageData{
+			RequestID:  requestID,
+			Status:     http.StatusRequestEntityTooLarge,
+			StatusText: http.StatusText(http.StatusRequestEntityTooLarge),
+			Timestamp:  time.Now(),
+		})
+		return
+	}
+	if err != nil {
+		log.Printf("[%s] Gagal membaca body request: %v", requestID, err)
+		http.Error(w, "Gagal membaca body request", http.StatusBadRequest)
+		return
+	}
 
-	// 3. Jalankan semua detektor
+	// 4. Jalankan detektor sesuai route policy, masing-masing dengan salinan request dan reader body baru
 	var allResults []detector.DetectionResult
-	for _, d := range rh.Detectors {
-		results, err := d.Detect(req)
-		if err != nil {
-			log.Printf("[%s] Error saat deteksi: %v", requestID, err)
+	for _, d := range c.Detectors {
+		if !route.RunsDetector(d.Name()) {
 			continue
 		}
-		if len(results) > 0 {
-			allResults = append(allResults, results...)
+		detectReq := req.WithContext(req.Context())
+		detectReq.Body = io.NopCloser(body.Reader())
+		results, err := d.Detect(detectReq)
+		for _, result := range results {
+			// File di luar batas ukuran atau daftar MIME route tidak ditangkap
+			if !route.Accepts(result) {
+				result.Close()
+				continue
+			}
+			result.Detector = d.Name()
+			metrics.ObserveDetection(d.Name(), sniffedMimeType(result.Content))
+			allResults = append(allResults, result)
+		}
+		if err != nil {
+			log.Printf("[%s] Error saat deteksi: %v", requestID, err)
 		}
 	}
 
-	// 4. Proses hasil deteksi secara asinkron
+	// 5. Proses hasil deteksi secara asinkron
+	var detections []evidence.Metadata
 	if len(allResults) > 0 {
-		rh.processDetections(req, requestID, allResults)
+		detections = rh.processDetections(c, req, requestID, route, clientCert, allResults)
 	}
 
-	// 5. Jalankan Coraza WAF
-	tx := rh.WAF.NewTransaction()
+	// Route dengan aksi "block" menolak request yang membawa file, setelah file bukti tercatat
+	if route.Action == policy.ActionBlock && len(detections) > 0 {
+		log.Printf("[%s] Request diblokir oleh route policy %s (%d file)", requestID, route.Name, len(detections))
+		c.BlockPage.Write(w, req, BlockPageData{
+			RequestID:  requestID,
+			Status:     http.StatusForbidden,
+			StatusText: http.StatusText(http.StatusForbidden),
+			Timestamp:  time.Now(),
+		})
+		return
+	}
+
+	// 6. Jalankan Coraza WAF, dengan rule set khusus route jika ada
+	wafEngine := c.WAF
+	if route.WAF() != nil {
+		wafEngine = route.WAF()
+	}
+	tx := wafEngine.NewTransaction()
 	defer func() {
 		tx.ProcessLogging()
 		tx.Close()
 	}()
 
+	if c.ExportDetections {
+		exportDetections(tx, detections)
+	}
+	exportTLS(tx, req.TLS, clientCert)
+
+	// Jalankan fase 1 (header) dan fase 2 (body) Coraza
+	it, err := processRequest(tx, req, body)
+	if err != nil {
+		log.Printf("[%s] Gagal memproses request di WAF: %v", requestID, err)
+		http.Error(w, "Gagal memproses request", http.StatusInternalServerError)
+		return
+	}
+
+	// Cek apakah ada interupsi setelah semua proses
+	if it != nil {
+		log.Printf("[%s] Request diblokir oleh WAF (rule %d, aksi %s)", requestID, it.RuleID, it.Action)
+		handleInterruption(w, req, c.BlockPage, requestID, it)
+		return
+	}
+
+	// 7. Teruskan request ke upstream route, atau upstream default
+	pool := c.Upstream
+	if route.Upstream() != nil {
+		pool = route.Upstream()
+	}
+	// Berikan body yang masih fresh ke proxy, dan izinkan upstream membacanya ulang saat retry
+	req.Body = io.NopCloser(body.Reader())
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(body.Reader()), nil
+	}
+	rh.proxy.ServeHTTP(w, withRequestState(req, &requestState{requestID: requestID, tx: tx, components: c, pool: pool}))
+}
+
+// rewrite menyiapkan request keluar untuk reverse proxy. Scheme dan host tujuan
+// diisi oleh upstream pool sesuai replika yang terpilih.
+func (rh *RequestHandler) rewrite(pr *httputil.ProxyRequest) {
+	var trusted []netip.Prefix
+	if state := requestStateFrom(pr.In.Context()); state != nil {
+		trusted = state.components.TrustedProxies
+	}
+	setForwardedHeaders(pr.Out, pr.In, trusted)
+}
+
+// poolTransport meneruskan request ke upstream pool yang dipilih untuk request tersebut,
+// sehingga satu reverse proxy bisa dipakai bersama oleh semua route.
+type poolTransport struct{}
+
+func (poolTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	state := requestStateFrom(req.Context())
+	if state == nil || state.pool == nil {
+		return nil, upstream.ErrNoTarget
+	}
+	return state.pool.RoundTrip(req)
+}
+
+// processRequest mengisi transaksi Coraza dengan data dari request (fase 1 dan 2)
+// dan berhenti pada interupsi pertama. Body yang sudah di-buffer dialirkan ke
+// transaksi sehingga rule body (misal: CRS SQLi/XSS) benar-benar memeriksa isinya.
+func processRequest(tx types.Transaction, req *http.Request, body *buffer.SpillBuffer) (*types.Interruption, error) {
 	// Ambil IP dan Port client
-	clientIP, clientPortStr, _ := strings.Cut(req.RemoteAddr, ":")
-	clientPort, _ := strconv.Atoi(clientPortStr)
+	clientIP, clientPort := splitRemoteAddr(req.RemoteAddr)
 
-	// KOREKSI: Gunakan 0 untuk port yang tidak diketahui, bukan ""
+	// Gunakan 0 untuk port server yang tidak diketahui
 	tx.ProcessConnection(clientIP, clientPort, "", 0)
-	tx.ProcessURI(req.Method, req.URL.String(), req.Proto)
+	tx.ProcessURI(req.URL.String(), req.Method, req.Proto)
 
 	// Menambahkan semua header request ke transaksi
 	for k, vv := range req.Header {
@@ -92,63 +259,112 @@ func (rh *RequestHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		}
 	}
 
+	// Header Host dan Transfer-Encoding dihapus dari req.Header oleh net/http,
+	// jadi kita tambahkan manual agar rule yang bergantung padanya tetap jalan.
+	if req.Host != "" {
+		tx.AddRequestHeader("Host", req.Host)
+		tx.SetServerName(req.Host)
+	}
+	if len(req.TransferEncoding) > 0 {
+		tx.AddRequestHeader("Transfer-Encoding", req.TransferEncoding[0])
+	}
+
 	// Proses header request
-	tx.ProcessRequestHeaders()
+	if it := tx.ProcessRequestHeaders(); it != nil {
+		metrics.ObserveInterruption(types.PhaseRequestHeaders, it)
+		return it, nil
+	}
 
-	// Kita hanya proses body jika Coraza belum terinterupsi oleh header.
-	if !tx.IsInterrupted() {
-		tx.ProcessRequestBody()
+	// Alirkan body ke transaksi. Batas SecRequestBodyLimit ditegakkan oleh Coraza:
+	// aksi Reject menghasilkan interupsi (413), ProcessPartial hanya menginspeksi
+	// bagian awal body hingga batas tersebut.
+	if tx.IsRequestBodyAccessible() && body.Size() > 0 {
+		it, _, err := tx.ReadRequestBodyFrom(body.Reader())
+		if err != nil {
+			return nil, fmt.Errorf("gagal menulis body request ke transaksi WAF: %w", err)
+		}
+		if it != nil {
+			metrics.ObserveInterruption(types.PhaseRequestBody, it)
+			return it, nil
+		}
 	}
 
-	// Cek apakah ada interupsi setelah semua proses
-	if tx.IsInterrupted() {
-		log.Printf("[%s] Request diblokir oleh WAF", requestID)
-		w.WriteHeader(http.StatusForbidden)
-		w.Write([]byte("Request diblokir oleh WAF"))
-		return
+	it, err := tx.ProcessRequestBody()
+	if it != nil {
+		metrics.ObserveInterruption(types.PhaseRequestBody, it)
 	}
+	return it, err
+}
 
-	// 6. Teruskan request ke backend
-	proxy := httputil.NewSingleHostReverseProxy(rh.Backend)
-	// Berikan body yang masih fresh ke proxy
-	req.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
-	proxy.ServeHTTP(w, req)
+// sniffedMimeType mengembalikan tipe MIME dari magic bytes tanpa parameter, untuk label
+// metrik. result.MimeType tidak dipakai karena bisa berasal dari Content-Type client,
+// sehingga client bisa membuat series baru di setiap request.
+func sniffedMimeType(content *buffer.SpillBuffer) string {
+	detected, err := mimetype.DetectReader(content.Reader())
+	if err != nil {
+		return "application/octet-stream"
+	}
+	base, _, _ := strings.Cut(detected.String(), ";")
+	return base
 }
 
-// processDetections menjalankan upload dan logging dalam goroutine.
-func (rh *RequestHandler) processDetections(req *http.Request, requestID string, results []detector.DetectionResult) {
-	for _, result := range results {
-		go func(res detector.DetectionResult) {
-			uniqueFilename := fmt.Sprintf("%s_%s", requestID, res.FileName)
-
-			// Upload file
-			uploadPath, err := rh.Uploader.Upload(context.Background(), bytes.NewReader(res.Data), uniqueFilename)
-			if err != nil {
-				log.Printf("[%s] Gagal upload file %s: %v", requestID, res.FileName, err)
-				return
-			}
+// splitRemoteAddr memisahkan IP dan port dari RemoteAddr, termasuk alamat IPv6.
+func splitRemoteAddr(remoteAddr string) (string, int) {
+	host, portStr, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr, 0
+	}
+	port, _ := strconv.Atoi(portStr)
+	return host, port
+}
 
-			// Buat event log
-			event := logger.LogEvent{
-				Timestamp:   time.Now(),
-				RequestID:   requestID,
-				Domain:      req.Host,
-				Path:        req.URL.Path,
-				Method:      req.Method,
-				RemoteAddr:  req.RemoteAddr,
-				FileName:    res.FileName,
-				FileSize:    int64(len(res.Data)),
-				MimeType:    res.MimeType,
-				UploadPath:  uploadPath,
-				SourceField: res.SourceField,
-			}
+// processDetections mengirim setiap file hasil deteksi ke worker pool untuk
+// di-hash, diunggah dan dicatat secara asinkron, lalu mengembalikan metadata-nya.
+// Jika hasil deteksi diekspor ke WAF, hash dihitung lebih dulu agar tersedia untuk rule.
+func (rh *RequestHandler) processDetections(c *Components, req *http.Request, requestID string, route *policy.Route, clientCert *evidence.ClientCert, results []detector.DetectionResult) []evidence.Metadata {
+	up := c.Uploader
+	if route.Uploader() != nil {
+		up = route.Uploader()
+	}
 
-			// Kirim ke semua logger aktif
-			for _, l := range rh.Loggers {
-				l.Log(event)
-			}
-			log.Printf("[%s] File terdeteksi dan diunggah: %s dari field %s", requestID, uploadPath, res.SourceField)
+	capturedAt := time.Now()
+	detections := make([]evidence.Metadata, 0, len(results))
+	for _, result := range results {
+		if len(result.Mismatches) > 0 {
+			log.Printf("[%s] PERINGATAN: File %s tidak sesuai dengan isinya (%s): dinyatakan %q, terdeteksi %q",
+				requestID, result.FileName, strings.Join(result.Mismatches, ", "), result.DeclaredMimeType, result.MimeType)
+		}
+		job := &evidenceJob{
+			result:    result,
+			uploader:  up,
+			spool:     rh.Spool,
+			extractor: c.Extractor,
+			meta: evidence.Metadata{
+				RequestID:    requestID,
+				OriginalName: result.FileName,
+				SourceField:  result.SourceField,
+				Detector:     result.Detector,
+				MimeType:     result.MimeType,
+				DeclaredMime: result.DeclaredMimeType,
+				Encoding:     result.Encoding,
+				Mismatches:   result.Mismatches,
+				Size:         result.Content.Size(),
+				Domain:       req.Host,
+				Path:         req.URL.Path,
+				Method:       req.Method,
+				RemoteAddr:   req.RemoteAddr,
+				CapturedAt:   capturedAt,
+				ClientCert:   clientCert,
+			},
+		}
 
-		}(result)
+		if c.ExportDetections {
+			if err := job.hash(); err != nil {
+				log.Printf("[%s] %v", requestID, err)
+			}
+		}
+		detections = append(detections, job.meta)
+		rh.Workers.Submit(job)
 	}
+	return detections
 }
@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/corazawaf/coraza/v3"
+	"github.com/luhtaf/corator/config"
+	"github.com/luhtaf/corator/detector"
+	"github.com/luhtaf/corator/metrics"
+	"github.com/luhtaf/corator/policy"
+	"github.com/luhtaf/corator/spool"
+	"github.com/luhtaf/corator/uploader"
+	"github.com/luhtaf/corator/upstream"
+	"github.com/luhtaf/corator/worker"
+)
+
+// testDirectives memblokir SQLi di argumen request (fase 2) dan kebocoran data di body
+// response (fase 4).
+const testDirectives = `
+SecRuleEngine On
+SecRequestBodyAccess On
+SecResponseBodyAccess On
+SecResponseBodyMimeType text/plain
+SecRule REQUEST_HEADERS:Content-Type "@beginsWith application/json" "id:100,phase:1,pass,nolog,ctl:requestBodyProcessor=JSON"
+SecRule ARGS "@detectSQLi" "id:1001,phase:2,deny,status:403,log,msg:'SQLi'"
+SecRule RESPONSE_BODY "@contains SECRET-TOKEN" "id:1002,phase:4,deny,status:403,log,msg:'Data leak'"
+`
+
+// testMaxBodySize adalah batas body request pada handler test.
+const testMaxBodySize = 64 << 10
+
+// newTestHandler membuat RequestHandler yang meneruskan request ke backend.
+func newTestHandler(t *testing.T, backend *httptest.Server) *RequestHandler {
+	t.Helper()
+
+	waf, err := coraza.NewWAF(coraza.NewWAFConfig().WithDirectives(testDirectives))
+	if err != nil {
+		t.Fatalf("NewWAF: %v", err)
+	}
+	pol, err := policy.New(config.PolicyConfig{}, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("policy.New: %v", err)
+	}
+	blockPage, err := NewBlockPage(config.BlockPageConfig{})
+	if err != nil {
+		t.Fatalf("NewBlockPage: %v", err)
+	}
+	errorPage, err := NewErrorPage(config.BlockPageConfig{})
+	if err != nil {
+		t.Fatalf("NewErrorPage: %v", err)
+	}
+	up, err := uploader.NewLocalUploader(config.LocalConfig{Path: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewLocalUploader: %v", err)
+	}
+	sp, err := spool.New(config.SpoolConfig{Path: t.TempDir(), RetryInterval: time.Minute, MinBackoff: time.Second, MaxBackoff: time.Minute}, []uploader.Uploader{up}, nil)
+	if err != nil {
+		t.Fatalf("spool.New: %v", err)
+	}
+	workers, err := worker.NewPool(config.WorkerConfig{Count: 1, QueueSize: 1, OverflowPolicy: worker.OverflowBlock})
+	if err != nil {
+		t.Fatalf("worker.NewPool: %v", err)
+	}
+	t.Cleanup(func() { workers.Shutdown(context.Background()) })
+
+	target, _ := url.Parse(backend.URL)
+	pool, err := upstream.NewSingle(target, http.DefaultTransport)
+	if err != nil {
+		t.Fatalf("upstream.NewSingle: %v", err)
+	}
+	return NewRequestHandler(&Components{
+		WAF:       waf,
+		Uploader:  up,
+		Policy:    pol,
+		BlockPage: blockPage,
+		ErrorPage: errorPage,
+		BufferCfg: config.BufferConfig{MemoryLimit: 1 << 20, TempDir: t.TempDir(), MaxBodySize: testMaxBodySize},
+		Upstream:  pool,
+	}, sp, workers)
+}
+
+func TestRequestBodySQLiBlockedAtPhase2(t *testing.T) {
+	var reached bool
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+	rh := newTestHandler(t, backend)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"urlencoded", "application/x-www-form-urlencoded", "user=admin&password=" + url.QueryEscape("' OR 1=1 --")},
+		{"json", "application/json", `{"user":"admin","password":"' OR 1=1 --"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reached = false
+			req := httptest.NewRequest("POST", "/login", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			rh.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("status = %d, seharusnya %d", rec.Code, http.StatusForbidden)
+			}
+			if reached {
+				t.Errorf("request yang diblokir tetap diteruskan ke backend")
+			}
+		})
+	}
+}
+
+func TestCleanRequestBodyForwarded(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write(body)
+	}))
+	defer backend.Close()
+	rh := newTestHandler(t, backend)
+
+	req := httptest.NewRequest("POST", "/login", strings.NewReader(`{"user":"admin","password":"hunter2"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	rh.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, seharusnya %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Body.String(); got != `{"user":"admin","password":"hunter2"}` {
+		t.Errorf("backend menerima body %q", got)
+	}
+}
+
+func TestResponseBodyBlockedAtPhase4(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, "api key: SECRET-TOKEN")
+	}))
+	defer backend.Close()
+	rh := newTestHandler(t, backend)
+
+	req := httptest.NewRequest("GET", "/profile", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	rh.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, seharusnya %d", rec.Code, http.StatusForbidden)
+	}
+	if strings.Contains(rec.Body.String(), "SECRET-TOKEN") {
+		t.Errorf("body response yang diblokir bocor ke client: %q", rec.Body.String())
+	}
+}
+
+func TestOversizedRequestBodyRejected(t *testing.T) {
+	var reached bool
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+	rh := newTestHandler(t, backend)
+
+	req := httptest.NewRequest("PUT", "/objects/dump.bin", strings.NewReader(strings.Repeat("A", testMaxBodySize+1)))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	rec := httptest.NewRecorder()
+	rh.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, seharusnya %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+	if reached {
+		t.Errorf("request dengan body terlalu besar tetap diteruskan ke backend")
+	}
+}
+
+func TestDetectionMetricIgnoresDeclaredMimeType(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+	rh := newTestHandler(t, backend)
+	c := rh.Components()
+	c.Detectors = []detector.Detector{detector.NewRawDetector([]string{"*/*"}, c.BufferCfg)}
+
+	// Isi biner yang tidak dikenali sniffing, sehingga tipe yang dinyatakan client dipakai sebagai MimeType
+	req := httptest.NewRequest("PUT", "/objects/blob", strings.NewReader("\x00\x01\x02corator-metric-test"))
+	req.Header.Set("Content-Type", "application/x-corator-fake-8f3a")
+	rh.ServeHTTP(httptest.NewRecorder(), req)
+
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	var found bool
+	for _, family := range families {
+		if family.GetName() != "corator_detections_total" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			for _, label := range m.GetLabel() {
+				if label.GetName() != "mime_type" {
+					continue
+				}
+				if strings.Contains(label.GetValue(), "corator-fake") {
+					t.Errorf("label mime_type memakai tipe dari client: %q", label.GetValue())
+				}
+				found = found || label.GetValue() == "application/octet-stream"
+			}
+		}
+	}
+	if !found {
+		t.Error("deteksi tidak dicatat dengan tipe hasil sniffing application/octet-stream")
+	}
+}
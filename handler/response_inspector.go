@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/corazawaf/coraza/v3/types"
+	"github.com/luhtaf/corator/metrics"
+	"github.com/luhtaf/corator/upstream"
+)
+
+// requestStateKey adalah key context untuk menyimpan state per-request.
+type requestStateKey struct{}
+
+// requestState menampung data yang dibutuhkan oleh callback reverse proxy
+// (ModifyResponse dan ErrorHandler) untuk request yang sedang diproses.
+type requestState struct {
+	requestID  string
+	tx         types.Transaction
+	components *Components    // Komponen yang dipakai request ini
+	pool       *upstream.Pool // Upstream tujuan request
+}
+
+// withRequestState menyisipkan state ke dalam context request.
+func withRequestState(req *http.Request, state *requestState) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), requestStateKey{}, state))
+}
+
+// requestStateFrom mengambil state dari context, nil jika tidak ada.
+func requestStateFrom(ctx context.Context) *requestState {
+	state, _ := ctx.Value(requestStateKey{}).(*requestState)
+	return state
+}
+
+// interruptionError menandai response backend yang diblokir WAF di fase 3/4.
+type interruptionError struct {
+	it *types.Interruption
+}
+
+func (e *interruptionError) Error() string {
+	return fmt.Sprintf("response diblokir oleh WAF (rule %d, aksi %s)", e.it.RuleID, e.it.Action)
+}
+
+// modifyResponse memasukkan status, header dan body response backend ke transaksi
+// Coraza yang sama dengan request-nya, sehingga rule fase 3 dan 4 ikut berjalan.
+func (rh *RequestHandler) modifyResponse(resp *http.Response) error {
+	state := requestStateFrom(resp.Request.Context())
+	if state == nil {
+		return nil
+	}
+
+	it, err := processResponse(state.tx, resp)
+	if err != nil {
+		return err
+	}
+	if it != nil {
+		return &interruptionError{it: it}
+	}
+	return nil
+}
+
+// proxyErrorHandler menangani error dari reverse proxy, termasuk response yang diblokir WAF.
+// Backend yang tidak merespons tepat waktu dijawab 504, error lain 502, keduanya dengan
+// halaman error yang memuat request ID.
+func (rh *RequestHandler) proxyErrorHandler(w http.ResponseWriter, req *http.Request, err error) {
+	requestID, c := "", rh.Components()
+	if state := requestStateFrom(req.Context()); state != nil {
+		requestID, c = state.requestID, state.components
+	}
+
+	var ie *interruptionError
+	if errors.As(err, &ie) {
+		log.Printf("[%s] Response diblokir oleh WAF (rule %d, aksi %s)", requestID, ie.it.RuleID, ie.it.Action)
+		handleInterruption(w, req, c.BlockPage, requestID, ie.it)
+		return
+	}
+
+	// Client sudah memutus koneksi, tidak ada yang perlu dijawab
+	if errors.Is(err, context.Canceled) && req.Context().Err() != nil {
+		log.Printf("[%s] Client memutus koneksi sebelum backend merespons", requestID)
+		return
+	}
+
+	status := http.StatusBadGateway
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout() {
+		status = http.StatusGatewayTimeout
+	}
+	log.Printf("[%s] Gagal meneruskan request ke backend (%d): %v", requestID, status, err)
+	c.ErrorPage.Write(w, req, BlockPageData{
+		RequestID:  requestID,
+		Status:     status,
+		StatusText: http.StatusText(status),
+		Timestamp:  time.Now(),
+	})
+}
+
+// processResponse menjalankan fase 3 (header) dan fase 4 (body) Coraza untuk response backend.
+// Jika tidak ada interupsi, body yang sudah di-buffer dikembalikan ke resp.Body agar
+// tetap bisa diteruskan ke client.
+func processResponse(tx types.Transaction, resp *http.Response) (*types.Interruption, error) {
+	for k, vv := range resp.Header {
+		for _, v := range vv {
+			tx.AddResponseHeader(k, v)
+		}
+	}
+
+	if it := tx.ProcessResponseHeaders(resp.StatusCode, resp.Proto); it != nil {
+		metrics.ObserveInterruption(types.PhaseResponseHeaders, it)
+		return it, nil
+	}
+
+	// Body hanya di-buffer jika rule membutuhkannya (SecResponseBodyAccess dan
+	// SecResponseBodyMimeType), selain itu response langsung di-stream ke client.
+	if !tx.IsResponseBodyAccessible() || !tx.IsResponseBodyProcessable() {
+		return nil, nil
+	}
+
+	it, _, err := tx.ReadResponseBodyFrom(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("gagal menulis body response ke transaksi WAF: %w", err)
+	}
+	if it != nil {
+		metrics.ObserveInterruption(types.PhaseResponseBody, it)
+		return it, nil
+	}
+
+	it, err = tx.ProcessResponseBody()
+	if err != nil {
+		return nil, fmt.Errorf("gagal memproses body response di WAF: %w", err)
+	}
+	if it != nil {
+		metrics.ObserveInterruption(types.PhaseResponseBody, it)
+		return it, nil
+	}
+
+	bodyReader, err := tx.ResponseBodyReader()
+	if err != nil {
+		return nil, fmt.Errorf("gagal membaca body response dari transaksi WAF: %w", err)
+	}
+
+	// Sisa body yang melebihi SecResponseBodyLimit (ProcessPartial) masih ada di resp.Body
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bodyReader, resp.Body), resp.Body}
+	return nil, nil
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProxyErrorHandlerStatus(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+	rh := newTestHandler(t, backend)
+
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		heading string
+	}{
+		{"koneksi ditolak", errors.New("dial tcp 10.0.0.1:8080: connect: connection refused"), http.StatusBadGateway, "Backend tidak dapat dijangkau"},
+		{"deadline context", fmt.Errorf("backend: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, "Backend tidak merespons"},
+		{"timeout jaringan", os.ErrDeadlineExceeded, http.StatusGatewayTimeout, "Backend tidak merespons"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := withRequestState(httptest.NewRequest("GET", "/", nil), &requestState{
+				requestID:  "req-123",
+				components: rh.Components(),
+			})
+			rec := httptest.NewRecorder()
+			rh.proxyErrorHandler(rec, req, tt.err)
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, seharusnya %d", rec.Code, tt.status)
+			}
+			body := rec.Body.String()
+			if !strings.Contains(body, tt.heading) || !strings.Contains(body, "req-123") {
+				t.Errorf("halaman error tidak memuat %q dan request ID: %q", tt.heading, body)
+			}
+		})
+	}
+}
+
+func TestProxyErrorHandlerClientCanceled(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+	rh := newTestHandler(t, backend)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rec := httptest.NewRecorder()
+	rh.proxyErrorHandler(rec, httptest.NewRequest("GET", "/", nil).WithContext(ctx), context.Canceled)
+
+	if rec.Body.Len() != 0 {
+		t.Errorf("client yang sudah pergi tetap dikirimi halaman error: %q", rec.Body.String())
+	}
+}
+
+func TestUnreachableBackendReturnsBadGateway(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	rh := newTestHandler(t, backend)
+	backend.Close()
+
+	rec := httptest.NewRecorder()
+	rh.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("status = %d, seharusnya 502", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "Backend tidak dapat dijangkau") {
+		t.Errorf("body bukan halaman error bawaan: %q", rec.Body.String())
+	}
+}
+
+func TestSlowBackendReturnsGatewayTimeout(t *testing.T) {
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer backend.Close()
+	defer close(release)
+	rh := newTestHandler(t, backend)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	rec := httptest.NewRecorder()
+	rh.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil).WithContext(ctx))
+
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("status = %d, seharusnya 504", rec.Code)
+	}
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"crypto/tls"
+	"strconv"
+	"strings"
+
+	"github.com/corazawaf/coraza/v3/experimental/plugins/plugintypes"
+	"github.com/corazawaf/coraza/v3/types"
+	"github.com/luhtaf/corator/evidence"
+)
+
+// exportDetections menyalin hasil deteksi ke variabel TX Coraza agar bisa dipakai
+// di SecRule, misalnya:
+//
+//	SecRule TX:corator_file_mime "@streq application/vnd.microsoft.portable-executable" "id:1001,phase:1,deny,status:403"
+//
+// Setiap atribut disimpan sebagai koleksi dengan satu nilai per file dan urutan yang
+// sama di semua variabel, seperti FILES dan FILES_SIZES. Variabel di-set sebelum
+// fase 1 sehingga tersedia di semua fase.
+func exportDetections(tx types.Transaction, detections []evidence.Metadata) {
+	state, ok := tx.(plugintypes.TransactionState)
+	if !ok {
+		return
+	}
+	vars := state.Variables().TX()
+
+	mismatched := 0
+	for _, meta := range detections {
+		vars.Add("corator_file_name", meta.OriginalName)
+		vars.Add("corator_file_size", strconv.FormatInt(meta.Size, 10))
+		vars.Add("corator_file_mime", meta.MimeType)
+		vars.Add("corator_file_declared_mime", meta.DeclaredMime)
+		vars.Add("corator_file_source", meta.SourceField)
+		vars.Add("corator_file_detector", meta.Detector)
+		vars.Add("corator_file_md5", meta.MD5)
+		vars.Add("corator_file_sha1", meta.SHA1)
+		vars.Add("corator_file_sha256", meta.SHA256)
+		vars.Add("corator_file_mismatch", strings.Join(meta.Mismatches, ","))
+
+		if len(meta.Mismatches) > 0 {
+			mismatched++
+			vars.Add("corator_mismatch_files", meta.OriginalName)
+		}
+	}
+	vars.Set("corator_detection_count", []string{strconv.Itoa(len(detections))})
+	vars.Set("corator_mismatch_count", []string{strconv.Itoa(mismatched)})
+}
+
+// exportTLS menyalin parameter koneksi TLS client dan identitas sertifikat client mTLS
+// ke variabel TX Coraza, sehingga rule bisa menolak misalnya versi TLS lama atau partner
+// yang tidak dikenal:
+//
+//	SecRule TX:corator_tls_version "@streq TLS 1.0" "id:1010,phase:1,deny,status:403"
+//	SecRule TX:corator_client_cert_fingerprint "!@pmFromFile partners.txt" "id:1011,phase:1,deny,status:403"
+//
+// Variabel hanya di-set untuk request yang diterima lewat listener HTTPS Corator, dan
+// variabel corator_client_cert_* hanya jika sertifikat client lolos verifikasi.
+func exportTLS(tx types.Transaction, cs *tls.ConnectionState, cert *evidence.ClientCert) {
+	state, ok := tx.(plugintypes.TransactionState)
+	if !ok || cs == nil {
+		return
+	}
+	vars := state.Variables().TX()
+
+	vars.Set("corator_tls_version", []string{tls.VersionName(cs.Version)})
+	vars.Set("corator_tls_cipher", []string{tls.CipherSuiteName(cs.CipherSuite)})
+	vars.Set("corator_tls_server_name", []string{cs.ServerName})
+	vars.Set("corator_tls_alpn", []string{cs.NegotiatedProtocol})
+
+	if cert == nil {
+		vars.Set("corator_client_cert_verified", []string{"0"})
+		return
+	}
+	vars.Set("corator_client_cert_verified", []string{"1"})
+	vars.Set("corator_client_cert_subject", []string{cert.Subject})
+	vars.Set("corator_client_cert_issuer", []string{cert.Issuer})
+	vars.Set("corator_client_cert_serial", []string{cert.Serial})
+	vars.Set("corator_client_cert_fingerprint", []string{cert.Fingerprint})
+	for _, san := range cert.SANs {
+		vars.Add("corator_client_cert_san", san)
+	}
+}
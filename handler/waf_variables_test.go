@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/corazawaf/coraza/v3"
+	"github.com/corazawaf/coraza/v3/experimental/plugins/plugintypes"
+	"github.com/corazawaf/coraza/v3/types"
+	"github.com/luhtaf/corator/evidence"
+)
+
+// newRuleTx membuat transaksi Coraza dengan satu rule tambahan.
+func newRuleTx(t *testing.T, rule string) types.Transaction {
+	t.Helper()
+	waf, err := coraza.NewWAF(coraza.NewWAFConfig().WithDirectives("SecRuleEngine On\n" + rule))
+	if err != nil {
+		t.Fatalf("NewWAF: %v", err)
+	}
+	tx := waf.NewTransaction()
+	t.Cleanup(func() { tx.Close() })
+	return tx
+}
+
+// txValues mengambil semua nilai variabel TX name.
+func txValues(tx types.Transaction, name string) []string {
+	var values []string
+	for _, m := range tx.(plugintypes.TransactionState).Variables().TX().FindString(name) {
+		values = append(values, m.Value())
+	}
+	return values
+}
+
+func TestExportDetectionsMatchedBySecRule(t *testing.T) {
+	detections := []evidence.Metadata{
+		{OriginalName: "laporan.pdf", Size: 1024, MimeType: "application/pdf", Detector: "file"},
+		{
+			OriginalName: "invoice.pdf", Size: 2048, MimeType: "application/vnd.microsoft.portable-executable",
+			DeclaredMime: "application/pdf", Detector: "file", Hashes: evidence.Hashes{SHA256: "abc123"},
+			Mismatches: []string{"extension", "declared"},
+		},
+	}
+
+	tests := []struct {
+		name string
+		rule string
+		want int
+	}{
+		{"MIME hasil sniffing", `SecRule TX:corator_file_mime "@streq application/vnd.microsoft.portable-executable" "id:1001,phase:1,deny,status:403"`, 1001},
+		{"jumlah mismatch", `SecRule TX:corator_mismatch_count "@gt 0" "id:1002,phase:1,deny,status:403"`, 1002},
+		{"jumlah deteksi", `SecRule TX:corator_detection_count "@eq 2" "id:1003,phase:1,deny,status:403"`, 1003},
+		{"tidak ada yang cocok", `SecRule TX:corator_file_mime "@streq application/x-executable" "id:1004,phase:1,deny,status:403"`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := newRuleTx(t, tt.rule)
+			exportDetections(tx, detections)
+
+			it := tx.ProcessRequestHeaders()
+			switch {
+			case tt.want == 0 && it != nil:
+				t.Errorf("rule %d memblokir request", it.RuleID)
+			case tt.want != 0 && (it == nil || it.RuleID != tt.want):
+				t.Errorf("interupsi = %+v, seharusnya dari rule %d", it, tt.want)
+			}
+		})
+	}
+
+	// Nilai per file disimpan dengan urutan yang sama di semua variabel
+	tx := newRuleTx(t, "")
+	exportDetections(tx, detections)
+	names, mismatch := txValues(tx, "corator_file_name"), txValues(tx, "corator_file_mismatch")
+	if len(names) != 2 || names[1] != "invoice.pdf" || len(mismatch) != 2 || mismatch[1] != "extension,declared" {
+		t.Errorf("corator_file_name = %q, corator_file_mismatch = %q", names, mismatch)
+	}
+	if got := txValues(tx, "corator_mismatch_files"); len(got) != 1 || got[0] != "invoice.pdf" {
+		t.Errorf("corator_mismatch_files = %q, seharusnya [invoice.pdf]", got)
+	}
+}
+
+func TestExportTLSMatchedBySecRule(t *testing.T) {
+	cs := &tls.ConnectionState{Version: tls.VersionTLS10, CipherSuite: tls.TLS_RSA_WITH_AES_128_CBC_SHA, ServerName: "shop.corator.test"}
+	cert := &evidence.ClientCert{Subject: "CN=partner-a", Fingerprint: "ab:cd", SANs: []string{"partner-a.test"}}
+
+	tx := newRuleTx(t, `SecRule TX:corator_tls_version "@streq TLS 1.0" "id:1010,phase:1,deny,status:403"`)
+	exportTLS(tx, cs, cert)
+	if it := tx.ProcessRequestHeaders(); it == nil || it.RuleID != 1010 {
+		t.Errorf("interupsi = %+v, seharusnya dari rule 1010", it)
+	}
+
+	tx = newRuleTx(t, `SecRule TX:corator_client_cert_fingerprint "@streq ab:cd" "id:1011,phase:1,deny,status:403"`)
+	exportTLS(tx, cs, cert)
+	if it := tx.ProcessRequestHeaders(); it == nil || it.RuleID != 1011 {
+		t.Errorf("interupsi = %+v, seharusnya dari rule 1011", it)
+	}
+	if got := txValues(tx, "corator_client_cert_verified"); len(got) != 1 || got[0] != "1" {
+		t.Errorf("corator_client_cert_verified = %q, seharusnya [1]", got)
+	}
+
+	// Tanpa sertifikat client hanya parameter TLS yang di-set
+	tx = newRuleTx(t, "")
+	exportTLS(tx, cs, nil)
+	if got := txValues(tx, "corator_client_cert_verified"); len(got) != 1 || got[0] != "0" {
+		t.Errorf("corator_client_cert_verified = %q, seharusnya [0]", got)
+	}
+	if got := txValues(tx, "corator_client_cert_subject"); len(got) != 0 {
+		t.Errorf("corator_client_cert_subject = %q tanpa sertifikat client", got)
+	}
+
+	// Request HTTP biasa tidak mendapat variabel TLS
+	tx = newRuleTx(t, "")
+	exportTLS(tx, nil, nil)
+	if got := txValues(tx, "corator_tls_version"); len(got) != 0 {
+		t.Errorf("corator_tls_version = %q untuk request tanpa TLS", got)
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"log"
+	"fmt"
 
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/luhtaf/corator/config"
@@ -31,23 +31,50 @@ func NewElasticLogger(cfg config.ElasticConfig) (*ElasticLogger, error) {
 	}, nil
 }
 
-// Log mengirimkan event ke Elasticsearch secara asinkron.
-func (l *ElasticLogger) Log(event LogEvent) {
-	// Jalankan dalam goroutine agar tidak memblokir proses utama
-	go func() {
-		body, err := json.Marshal(event)
-		if err != nil {
-			log.Printf("ElasticLogger: Gagal marshal log event: %v", err)
-			return
-		}
-
-		_, err = l.client.Index(
-			l.index,
-			bytes.NewReader(body),
-			l.client.Index.WithContext(context.Background()),
-		)
-		if err != nil {
-			log.Printf("ElasticLogger: Gagal mengirim log: %v", err)
-		}
-	}()
+// Name mengembalikan nama logger.
+func (l *ElasticLogger) Name() string {
+	return "elastic"
+}
+
+// Check memastikan cluster Elasticsearch merespons.
+func (l *ElasticLogger) Check(ctx context.Context) error {
+	res, err := l.client.Ping(l.client.Ping.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("Elasticsearch tidak merespons: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("Elasticsearch merespons dengan status %s", res.Status())
+	}
+	return nil
+}
+
+// Close tidak melakukan apa-apa; client Elasticsearch tidak memegang resource yang
+// perlu dilepas selain koneksi idle.
+func (l *ElasticLogger) Close() error {
+	return nil
+}
+
+// Log mengirimkan event ke Elasticsearch.
+func (l *ElasticLogger) Log(ctx context.Context, event LogEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("ElasticLogger: gagal marshal log event: %w", err)
+	}
+
+	res, err := l.client.Index(
+		l.index,
+		bytes.NewReader(body),
+		l.client.Index.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("ElasticLogger: gagal mengirim log: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("ElasticLogger: Elasticsearch menolak log: %s", res.Status())
+	}
+	return nil
 }
@@ -1,19 +1,23 @@
 package logger
 
 import (
+	"errors"
+	"fmt"
 	"log"
 
 	"github.com/luhtaf/corator/config"
 )
 
-// NewLoggers adalah factory yang membuat semua logger yang aktif berdasarkan konfigurasi.
-func NewLoggers(cfg *config.Config) []Logger {
+// OpenLoggers membuat semua logger yang aktif dan mengembalikan logger yang berhasil
+// dibuat beserta error gabungan untuk logger yang gagal diinisialisasi.
+func OpenLoggers(cfg *config.Config) ([]Logger, error) {
 	var activeLoggers []Logger
+	var errs []error
 
 	if cfg.Logger.EnableFile {
 		fileLogger, err := NewFileLogger(cfg.Logger.File)
 		if err != nil {
-			log.Printf("PERINGATAN: Gagal menginisialisasi FileLogger: %v", err)
+			errs = append(errs, fmt.Errorf("gagal menginisialisasi FileLogger: %w", err))
 		} else {
 			log.Println("FileLogger aktif.")
 			activeLoggers = append(activeLoggers, fileLogger)
@@ -23,12 +27,23 @@ func NewLoggers(cfg *config.Config) []Logger {
 	if cfg.Logger.EnableElastic {
 		elasticLogger, err := NewElasticLogger(cfg.Logger.Elastic)
 		if err != nil {
-			log.Printf("PERINGATAN: Gagal menginisialisasi ElasticLogger: %v", err)
+			errs = append(errs, fmt.Errorf("gagal menginisialisasi ElasticLogger: %w", err))
 		} else {
 			log.Println("ElasticLogger aktif.")
 			activeLoggers = append(activeLoggers, elasticLogger)
 		}
 	}
 
-	return activeLoggers
+	return activeLoggers, errors.Join(errs...)
+}
+
+// CloseLoggers menutup semua logger dan mengembalikan error gabungan.
+func CloseLoggers(loggers []Logger) error {
+	var errs []error
+	for _, l := range loggers {
+		if err := l.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("gagal menutup logger %s: %w", l.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
 }
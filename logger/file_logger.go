@@ -1,7 +1,10 @@
 package logger
 
 import (
+	"context"
+	"io"
 	"os"
+	"sync"
 
 	"github.com/luhtaf/corator/config" // Nama package diganti sesuai modul Anda
 	"github.com/rs/zerolog"
@@ -9,6 +12,9 @@ import (
 
 // FileLogger adalah implementasi logger yang menulis ke file lokal.
 type FileLogger struct {
+	mu     sync.Mutex
+	file   *os.File
+	out    *errorRecorder
 	logger zerolog.Logger
 }
 
@@ -25,14 +31,38 @@ func NewFileLogger(cfg config.FileLoggerConfig) (*FileLogger, error) {
 
 	// Menggunakan timestamp Unix dan pesan default dari zerolog
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
-	logger := zerolog.New(file).With().Timestamp().Logger()
+	out := &errorRecorder{w: file}
+	logger := zerolog.New(out).With().Timestamp().Logger()
 
-	return &FileLogger{logger: logger}, nil
+	return &FileLogger{file: file, out: out, logger: logger}, nil
+}
+
+// Name mengembalikan nama logger.
+func (l *FileLogger) Name() string {
+	return "file"
+}
+
+// Check memastikan file log masih bisa diakses.
+func (l *FileLogger) Check(ctx context.Context) error {
+	_, err := l.file.Stat()
+	return err
+}
+
+// Close menutup file log. Event yang dicatat setelahnya gagal dan disimpan ke spool
+// untuk logger pengganti.
+func (l *FileLogger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
 }
 
 // Log mencatat event ke file.
-func (l *FileLogger) Log(event LogEvent) {
-	l.logger.Info().
+func (l *FileLogger) Log(ctx context.Context, event LogEvent) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.out.err = nil
+	entry := l.logger.Info().
 		Str("request_id", event.RequestID).
 		Str("domain", event.Domain).
 		Str("path", event.Path).
@@ -43,5 +73,48 @@ func (l *FileLogger) Log(event LogEvent) {
 		Str("mime_type", event.MimeType).
 		Str("upload_path", event.UploadPath).
 		Str("source_field", event.SourceField).
-		Msg("file intercepted")
+		Str("detector", event.Detector).
+		Str("md5", event.MD5).
+		Str("sha1", event.SHA1).
+		Str("sha256", event.SHA256)
+
+	// Field opsional hanya ditulis jika terisi, sama seperti omitempty di LogEvent
+	if event.DeclaredMime != "" {
+		entry = entry.Str("declared_mime_type", event.DeclaredMime)
+	}
+	if event.Encoding != "" {
+		entry = entry.Str("encoding", event.Encoding)
+	}
+	if len(event.Mismatches) > 0 {
+		entry = entry.Strs("mismatches", event.Mismatches)
+	}
+	if event.ArchivePath != "" {
+		entry = entry.Str("archive_path", event.ArchivePath).Str("parent_sha256", event.ParentSHA256)
+	}
+	if cert := event.ClientCert; cert != nil {
+		entry = entry.Dict("client_cert", zerolog.Dict().
+			Str("subject", cert.Subject).
+			Str("issuer", cert.Issuer).
+			Strs("sans", cert.SANs).
+			Str("serial", cert.Serial).
+			Str("fingerprint_sha256", cert.Fingerprint))
+	}
+
+	entry.Msg("file intercepted")
+	return l.out.err
+}
+
+// errorRecorder menyimpan error tulis terakhir, karena zerolog tidak mengembalikan
+// error dari Msg ke pemanggil.
+type errorRecorder struct {
+	w   io.Writer
+	err error
+}
+
+func (r *errorRecorder) Write(p []byte) (int, error) {
+	n, err := r.w.Write(p)
+	if err != nil {
+		r.err = err
+	}
+	return n, err
 }
@@ -0,0 +1,39 @@
+package logger
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/luhtaf/corator/config"
+)
+
+func TestFileLoggerClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "corator.log")
+	l, err := NewFileLogger(config.FileLoggerConfig{Path: path})
+	if err != nil {
+		t.Fatalf("NewFileLogger: %v", err)
+	}
+
+	if err := l.Log(context.Background(), LogEvent{RequestID: "sebelum-close"}); err != nil {
+		t.Fatalf("Log: %v", err)
+	}
+	if err := CloseLoggers([]Logger{l}); err != nil {
+		t.Fatalf("CloseLoggers: %v", err)
+	}
+
+	// Event setelah Close harus gagal agar spool mengirimkannya ke logger pengganti
+	if err := l.Log(context.Background(), LogEvent{RequestID: "setelah-close"}); err == nil {
+		t.Errorf("Log setelah Close seharusnya gagal")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "sebelum-close") || strings.Contains(string(data), "setelah-close") {
+		t.Errorf("isi file log tidak sesuai: %s", data)
+	}
+}
@@ -1,24 +1,78 @@
 package logger
 
-import "time"
+import (
+	"context"
+	"time"
+
+	"github.com/luhtaf/corator/evidence"
+)
 
 // LogEvent adalah struktur standar untuk setiap entri log.
 // Menggunakan format JSON yang ramah untuk Elastic/SIEM.
 type LogEvent struct {
-	Timestamp   time.Time `json:"@timestamp"`
-	RequestID   string    `json:"request_id"`
-	Domain      string    `json:"domain"`
-	Path        string    `json:"path"`
-	Method      string    `json:"method"`
-	RemoteAddr  string    `json:"remote_addr"`
-	FileName    string    `json:"file_name"`
-	FileSize    int64     `json:"file_size"`
-	MimeType    string    `json:"mime_type"`
-	UploadPath  string    `json:"upload_path"`
-	SourceField string    `json:"source_field"`
+	Timestamp    time.Time `json:"@timestamp"`
+	RequestID    string    `json:"request_id"`
+	Domain       string    `json:"domain"`
+	Path         string    `json:"path"`
+	Method       string    `json:"method"`
+	RemoteAddr   string    `json:"remote_addr"`
+	FileName     string    `json:"file_name"`
+	FileSize     int64     `json:"file_size"`
+	MimeType     string    `json:"mime_type"`
+	DeclaredMime string    `json:"declared_mime_type,omitempty"`
+	Encoding     string    `json:"encoding,omitempty"`
+	Mismatches   []string  `json:"mismatches,omitempty"`
+	UploadPath   string    `json:"upload_path"`
+	SourceField  string    `json:"source_field"`
+	Detector     string    `json:"detector"`
+	MD5          string    `json:"md5"`
+	SHA1         string    `json:"sha1"`
+	SHA256       string    `json:"sha256"`
+
+	// Diisi untuk member yang diekstrak dari archive, menautkan event ke archive induknya
+	ArchivePath  string `json:"archive_path,omitempty"`
+	ParentSHA256 string `json:"parent_sha256,omitempty"`
+
+	// Identitas client mTLS yang mengirim file, nil jika tanpa sertifikat client
+	ClientCert *evidence.ClientCert `json:"client_cert,omitempty"`
+}
+
+// NewLogEvent membuat LogEvent dari metadata file bukti yang sudah diunggah.
+func NewLogEvent(meta evidence.Metadata, uploadPath string) LogEvent {
+	return LogEvent{
+		Timestamp:    meta.CapturedAt,
+		RequestID:    meta.RequestID,
+		Domain:       meta.Domain,
+		Path:         meta.Path,
+		Method:       meta.Method,
+		RemoteAddr:   meta.RemoteAddr,
+		FileName:     meta.OriginalName,
+		FileSize:     meta.Size,
+		MimeType:     meta.MimeType,
+		DeclaredMime: meta.DeclaredMime,
+		Encoding:     meta.Encoding,
+		Mismatches:   meta.Mismatches,
+		UploadPath:   uploadPath,
+		SourceField:  meta.SourceField,
+		Detector:     meta.Detector,
+		MD5:          meta.MD5,
+		SHA1:         meta.SHA1,
+		SHA256:       meta.SHA256,
+		ArchivePath:  meta.ArchivePath,
+		ParentSHA256: meta.ParentSHA256,
+		ClientCert:   meta.ClientCert,
+	}
 }
 
 // Logger adalah interface umum untuk semua implementasi logger.
 type Logger interface {
-	Log(event LogEvent)
+	// Name mengembalikan nama unik logger, dipakai untuk mengirim ulang event yang gagal.
+	Name() string
+	// Log mengirim event dan mengembalikan error jika event gagal dicatat. Pengiriman
+	// dibatalkan saat ctx habis, misalnya saat batas waktu drain terlewati.
+	Log(ctx context.Context, event LogEvent) error
+	// Check memastikan tujuan log bisa dipakai, untuk readiness probe.
+	Check(ctx context.Context) error
+	// Close melepaskan resource logger setelah logger diganti saat reload.
+	Close() error
 }
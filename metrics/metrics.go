@@ -0,0 +1,192 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/corazawaf/coraza/v3/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "corator"
+
+// Registry adalah registry Prometheus untuk semua metrik Corator.
+var Registry = prometheus.NewRegistry()
+
+var (
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Jumlah request yang diproses proxy, per method dan status code.",
+	}, []string{"method", "code"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latensi request end-to-end, per method dan status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	wafInterruptions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "waf_interruptions_total",
+		Help:      "Jumlah interupsi WAF, per rule ID, fase dan aksi.",
+	}, []string{"rule_id", "phase", "action"})
+
+	detections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "detections_total",
+		Help:      "Jumlah file yang terdeteksi, per detektor dan tipe MIME hasil sniffing.",
+	}, []string{"detector", "mime_type"})
+
+	uploadsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "uploads_total",
+		Help:      "Jumlah upload file bukti, per backend uploader dan hasil.",
+	}, []string{"backend", "result"})
+
+	uploadDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upload_duration_seconds",
+		Help:      "Latensi upload file bukti, per backend uploader.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"backend"})
+
+	loggerFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logger_failures_total",
+		Help:      "Jumlah log event yang gagal dicatat, per logger.",
+	}, []string{"logger"})
+
+	upstreamTargetUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "upstream_target_up",
+		Help:      "Status health check aktif replika upstream (1 sehat, 0 tidak sehat).",
+	}, []string{"upstream", "target"})
+
+	upstreamEjections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_ejections_total",
+		Help:      "Jumlah replika upstream yang dikeluarkan karena error berturut-turut.",
+	}, []string{"upstream", "target"})
+
+	configReloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "config_reloads_total",
+		Help:      "Jumlah reload konfigurasi, per hasil.",
+	}, []string{"result"})
+
+	certificateExpiry = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "tls_certificate_expiry_timestamp_seconds",
+		Help:      "Waktu kedaluwarsa sertifikat listener HTTPS (Unix timestamp), per file sertifikat.",
+	}, []string{"certificate"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		requestsTotal,
+		requestDuration,
+		wafInterruptions,
+		detections,
+		uploadsTotal,
+		uploadDuration,
+		loggerFailures,
+		configReloads,
+		upstreamTargetUp,
+		upstreamEjections,
+		certificateExpiry,
+	)
+}
+
+// Handler mengembalikan http.Handler untuk endpoint /metrics.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// InstrumentHandler membungkus handler proxy untuk mencatat jumlah dan latensi request.
+func InstrumentHandler(h http.Handler) http.Handler {
+	return promhttp.InstrumentHandlerCounter(requestsTotal,
+		promhttp.InstrumentHandlerDuration(requestDuration, h))
+}
+
+// ObserveInterruption mencatat interupsi WAF pada fase tertentu.
+func ObserveInterruption(phase types.RulePhase, it *types.Interruption) {
+	wafInterruptions.WithLabelValues(strconv.Itoa(it.RuleID), strconv.Itoa(int(phase)), it.Action).Inc()
+}
+
+// ObserveDetection mencatat satu file yang terdeteksi.
+func ObserveDetection(detector, mimeType string) {
+	detections.WithLabelValues(detector, mimeType).Inc()
+}
+
+// ObserveUpload mencatat hasil dan latensi satu upload.
+func ObserveUpload(backend string, started time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	uploadsTotal.WithLabelValues(backend, result).Inc()
+	uploadDuration.WithLabelValues(backend).Observe(time.Since(started).Seconds())
+}
+
+// ObserveLoggerFailure mencatat satu log event yang gagal dicatat.
+func ObserveLoggerFailure(logger string) {
+	loggerFailures.WithLabelValues(logger).Inc()
+}
+
+// ObserveReload mencatat hasil satu reload konfigurasi.
+func ObserveReload(err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	configReloads.WithLabelValues(result).Inc()
+}
+
+// SetUpstreamTargetUp mencatat hasil health check aktif satu replika upstream.
+func SetUpstreamTargetUp(upstream, target string, up bool) {
+	value := 0.0
+	if up {
+		value = 1
+	}
+	upstreamTargetUp.WithLabelValues(upstream, target).Set(value)
+}
+
+// ObserveUpstreamEjection mencatat satu replika upstream yang dikeluarkan sementara.
+func ObserveUpstreamEjection(upstream, target string) {
+	upstreamEjections.WithLabelValues(upstream, target).Inc()
+}
+
+// SetCertificateExpiries mengganti waktu kedaluwarsa semua sertifikat yang sedang dipakai,
+// sehingga sertifikat yang sudah tidak dimuat tidak lagi dilaporkan.
+func SetCertificateExpiries(expiries map[string]time.Time) {
+	certificateExpiry.Reset()
+	for certificate, notAfter := range expiries {
+		certificateExpiry.WithLabelValues(certificate).Set(float64(notAfter.Unix()))
+	}
+}
+
+// RegisterGaugeFunc mendaftarkan gauge yang nilainya dibaca dari fn setiap kali di-scrape,
+// misalnya kedalaman antrean worker atau spool.
+func RegisterGaugeFunc(name, help string, fn func() float64) {
+	Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, fn))
+}
+
+// RegisterCounterFunc mendaftarkan counter yang nilainya dibaca dari fn setiap kali di-scrape.
+func RegisterCounterFunc(name, help string, fn func() float64) {
+	Registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, fn))
+}
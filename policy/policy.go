@@ -0,0 +1,274 @@
+package policy
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/corazawaf/coraza/v3"
+	"github.com/luhtaf/corator/config"
+	"github.com/luhtaf/corator/detector"
+	"github.com/luhtaf/corator/uploader"
+	"github.com/luhtaf/corator/upstream"
+)
+
+// Aksi route terhadap request yang cocok.
+const (
+	ActionRecord = "record" // Tangkap file bukti, request tetap diteruskan (default)
+	ActionBlock  = "block"  // Tangkap file bukti lalu blokir request jika ada file yang tertangkap
+	ActionIgnore = "ignore" // Jangan jalankan detektor sama sekali
+)
+
+// Route adalah satu aturan intersepsi yang sudah dikompilasi.
+type Route struct {
+	Name   string
+	Action string
+
+	hosts       []*regexp.Regexp
+	paths       []*regexp.Regexp
+	methods     []string
+	detectors   []string
+	maxFileSize int64
+	allowMime   []string
+	denyMime    []string
+	uploader    uploader.Uploader
+	upstream    *upstream.Pool
+	waf         coraza.WAF
+
+	requireClientCert bool
+}
+
+// Policy memilih route untuk setiap request. Route dicocokkan berurutan dan route
+// pertama yang cocok dipakai; request yang tidak cocok memakai route default.
+type Policy struct {
+	routes       []*Route
+	defaultRoute *Route
+}
+
+// New mengompilasi route dari konfigurasi. detectorNames adalah nama detektor yang
+// aktif, uploaders adalah uploader yang bisa dipilih route berdasarkan namanya,
+// wafs adalah WAF per path konfigurasi Coraza (lihat waf.NewWAFs) dan pools adalah
+// upstream per nama atau URL backend (lihat upstream.NewPools).
+func New(cfg config.PolicyConfig, detectorNames []string, uploaders []uploader.Uploader, wafs map[string]coraza.WAF, pools map[string]*upstream.Pool) (*Policy, error) {
+	p := &Policy{defaultRoute: &Route{Name: "default", Action: ActionRecord}}
+
+	for i, rc := range cfg.Routes {
+		name := rc.Name
+		if name == "" {
+			name = fmt.Sprintf("route-%d", i+1)
+		}
+
+		route, err := newRoute(name, rc, detectorNames, uploaders, wafs, pools)
+		if err != nil {
+			return nil, fmt.Errorf("route %s tidak valid: %w", name, err)
+		}
+		p.routes = append(p.routes, route)
+	}
+
+	return p, nil
+}
+
+// newRoute memvalidasi dan mengompilasi satu route.
+func newRoute(name string, rc config.RouteConfig, detectorNames []string, uploaders []uploader.Uploader, wafs map[string]coraza.WAF, pools map[string]*upstream.Pool) (*Route, error) {
+	route := &Route{
+		Name:        name,
+		Action:      strings.ToLower(rc.Action),
+		maxFileSize: rc.MaxFileSize,
+		allowMime:   lower(rc.AllowMime),
+		denyMime:    lower(rc.DenyMime),
+
+		requireClientCert: rc.RequireClientCert,
+	}
+
+	switch route.Action {
+	case "":
+		route.Action = ActionRecord
+	case ActionRecord, ActionBlock, ActionIgnore:
+	default:
+		return nil, fmt.Errorf("aksi tidak dikenal: %s", rc.Action)
+	}
+
+	for _, host := range rc.Hosts {
+		re, err := compilePattern(strings.ToLower(host))
+		if err != nil {
+			return nil, fmt.Errorf("pola host %q: %w", host, err)
+		}
+		route.hosts = append(route.hosts, re)
+	}
+
+	for _, path := range rc.Paths {
+		re, err := compilePattern(path)
+		if err != nil {
+			return nil, fmt.Errorf("pola path %q: %w", path, err)
+		}
+		route.paths = append(route.paths, re)
+	}
+
+	for _, method := range rc.Methods {
+		route.methods = append(route.methods, strings.ToUpper(method))
+	}
+
+	for _, d := range rc.Detectors {
+		if !slices.Contains(detectorNames, d) {
+			return nil, fmt.Errorf("detektor %q tidak aktif", d)
+		}
+		route.detectors = append(route.detectors, d)
+	}
+
+	if rc.Uploader != "" {
+		idx := slices.IndexFunc(uploaders, func(up uploader.Uploader) bool { return up.Name() == rc.Uploader })
+		if idx < 0 {
+			return nil, fmt.Errorf("uploader %q tidak tersedia", rc.Uploader)
+		}
+		route.uploader = uploaders[idx]
+	}
+
+	if key := rc.Upstream + rc.Backend; key != "" {
+		route.upstream = pools[key]
+		if route.upstream == nil {
+			return nil, fmt.Errorf("upstream %q tidak tersedia", key)
+		}
+	}
+
+	if rc.CorazaConfigPath != "" {
+		route.waf = wafs[rc.CorazaConfigPath]
+		if route.waf == nil {
+			return nil, fmt.Errorf("rule set Coraza %q belum dimuat", rc.CorazaConfigPath)
+		}
+	}
+
+	return route, nil
+}
+
+// Match mengembalikan route untuk request, atau route default jika tidak ada yang cocok.
+func (p *Policy) Match(req *http.Request) *Route {
+	for _, route := range p.routes {
+		if route.matches(req) {
+			return route
+		}
+	}
+	return p.defaultRoute
+}
+
+// matches mengembalikan true jika host, path dan method request cocok dengan route.
+// Kriteria yang kosong dianggap cocok dengan semua request.
+func (r *Route) matches(req *http.Request) bool {
+	if len(r.methods) > 0 && !slices.Contains(r.methods, req.Method) {
+		return false
+	}
+
+	host := strings.ToLower(req.Host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if len(r.hosts) > 0 && !matchAny(r.hosts, host) {
+		return false
+	}
+
+	return len(r.paths) == 0 || matchAny(r.paths, cleanPath(req.URL.Path))
+}
+
+// cleanPath menormalkan path request sebelum dicocokkan, agar "//", "/./" dan "/../"
+// tidak bisa dipakai untuk menghindari route. Garis miring di akhir path dipertahankan.
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	cleaned := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// RunsDetector mengembalikan true jika detektor dengan nama tersebut dijalankan untuk route ini.
+func (r *Route) RunsDetector(name string) bool {
+	if r.Action == ActionIgnore {
+		return false
+	}
+	return len(r.detectors) == 0 || slices.Contains(r.detectors, name)
+}
+
+// Accepts mengembalikan true jika file hasil deteksi lolos batas ukuran dan daftar MIME route.
+func (r *Route) Accepts(result detector.DetectionResult) bool {
+	if r.maxFileSize > 0 && result.Content.Size() > r.maxFileSize {
+		return false
+	}
+
+	mimeType, _, _ := strings.Cut(strings.ToLower(result.MimeType), ";")
+	mimeType = strings.TrimSpace(mimeType)
+	if matchMIME(r.denyMime, mimeType) {
+		return false
+	}
+	return len(r.allowMime) == 0 || matchMIME(r.allowMime, mimeType)
+}
+
+// Uploader mengembalikan uploader tujuan route, atau nil untuk memakai uploader default.
+func (r *Route) Uploader() uploader.Uploader {
+	return r.uploader
+}
+
+// Upstream mengembalikan upstream pool route, atau nil untuk memakai backend default.
+func (r *Route) Upstream() *upstream.Pool {
+	return r.upstream
+}
+
+// WAF mengembalikan WAF dengan rule set khusus route, atau nil untuk memakai WAF global.
+func (r *Route) WAF() coraza.WAF {
+	return r.waf
+}
+
+// RequiresClientCert mengembalikan true jika route hanya menerima client dengan sertifikat
+// mTLS yang terverifikasi.
+func (r *Route) RequiresClientCert() bool {
+	return r.requireClientCert
+}
+
+// compilePattern mengompilasi pola host atau path. Pola berawalan "~" adalah regex,
+// selain itu glob dengan "*" (termasuk "/") dan "?" yang harus cocok dengan seluruh nilai.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if expr, ok := strings.CutPrefix(pattern, "~"); ok {
+		return regexp.Compile(expr)
+	}
+
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, `\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\?`, ".")
+	return regexp.Compile("^" + expr + "$")
+}
+
+// matchAny mengembalikan true jika value cocok dengan salah satu pola.
+func matchAny(patterns []*regexp.Regexp, value string) bool {
+	for _, re := range patterns {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchMIME mengembalikan true jika tipe MIME cocok dengan salah satu pola, mendukung "image/*".
+func matchMIME(patterns []string, mimeType string) bool {
+	for _, p := range patterns {
+		if p == mimeType || p == "*/*" {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(p, "/*"); ok && strings.HasPrefix(mimeType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// lower mengubah semua nilai menjadi huruf kecil.
+func lower(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		out = append(out, strings.ToLower(strings.TrimSpace(v)))
+	}
+	return out
+}
@@ -0,0 +1,121 @@
+package policy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/corazawaf/coraza/v3"
+	"github.com/luhtaf/corator/buffer"
+	"github.com/luhtaf/corator/config"
+	"github.com/luhtaf/corator/detector"
+	"github.com/luhtaf/corator/upstream"
+)
+
+// nonCanonicalPaths adalah variasi path yang harus tetap cocok dengan route "/api/b2b/*".
+var nonCanonicalPaths = []string{
+	"/api/b2b/upload",
+	"/x/../api/b2b/upload",
+	"//api/b2b/upload",
+	"/api/./b2b/upload",
+}
+
+func TestMatchRequireClientCertOnNonCanonicalPath(t *testing.T) {
+	p, err := New(config.PolicyConfig{Routes: []config.RouteConfig{
+		{Name: "partner", Paths: []string{"/api/b2b/*"}, RequireClientCert: true},
+	}}, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	for _, path := range nonCanonicalPaths {
+		req := httptest.NewRequest("POST", "http://example.com/", nil)
+		req.URL.Path = path
+		route := p.Match(req)
+		if route.Name != "partner" || !route.RequiresClientCert() {
+			t.Errorf("path %q cocok dengan route %q, seharusnya partner", path, route.Name)
+		}
+	}
+}
+
+func TestMatchRouteWAFAndUpstreamOnNonCanonicalPath(t *testing.T) {
+	routeWAF, err := coraza.NewWAF(coraza.NewWAFConfig())
+	if err != nil {
+		t.Fatalf("NewWAF: %v", err)
+	}
+	target, _ := url.Parse("http://billing:8080")
+	pool, err := upstream.NewSingle(target, http.DefaultTransport)
+	if err != nil {
+		t.Fatalf("NewSingle: %v", err)
+	}
+
+	p, err := New(config.PolicyConfig{Routes: []config.RouteConfig{
+		{Name: "b2b", Paths: []string{"/api/b2b/*"}, Backend: target.String(), CorazaConfigPath: "b2b.conf"},
+	}}, nil, nil, map[string]coraza.WAF{"b2b.conf": routeWAF}, map[string]*upstream.Pool{target.String(): pool})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	for _, path := range nonCanonicalPaths {
+		req := httptest.NewRequest("POST", "http://example.com/", nil)
+		req.URL.Path = path
+		route := p.Match(req)
+		if route.WAF() != routeWAF || route.Upstream() != pool {
+			t.Errorf("path %q tidak memakai rule set dan upstream route b2b (route %q)", path, route.Name)
+		}
+	}
+}
+
+func TestMatchActionAndLimitsOnNonCanonicalPath(t *testing.T) {
+	p, err := New(config.PolicyConfig{Routes: []config.RouteConfig{
+		{Name: "ignored", Paths: []string{"/api/b2b/health"}, Action: ActionIgnore},
+		{Name: "blocked", Paths: []string{"/api/b2b/*"}, Action: ActionBlock, MaxFileSize: 4, DenyMime: []string{"image/*"}},
+	}}, []string{"file"}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	content := buffer.New(1024, t.TempDir())
+	defer content.Close()
+	content.Write([]byte("abc"))
+	png := detector.DetectionResult{Content: content, MimeType: "image/png"}
+
+	for _, path := range nonCanonicalPaths {
+		req := httptest.NewRequest("POST", "http://example.com/", nil)
+		req.URL.Path = path
+		route := p.Match(req)
+		if route.Action != ActionBlock {
+			t.Errorf("path %q memakai aksi %q, seharusnya block", path, route.Action)
+		}
+		if route.Accepts(png) {
+			t.Errorf("path %q menerima MIME yang ditolak route", path)
+		}
+	}
+
+	for _, path := range []string{"/api/b2b/./health", "//api/b2b/health", "/api/x/../b2b/health"} {
+		req := httptest.NewRequest("GET", "http://example.com/", nil)
+		req.URL.Path = path
+		if route := p.Match(req); route.RunsDetector("file") {
+			t.Errorf("path %q menjalankan detektor pada route ignore (route %q)", path, route.Name)
+		}
+	}
+}
+
+func TestCleanPath(t *testing.T) {
+	tests := map[string]string{
+		"":                   "/",
+		"/":                  "/",
+		"//":                 "/",
+		"/a//b/":             "/a/b/",
+		"/a/./b":             "/a/b",
+		"/a/../../b":         "/b",
+		"a/b":                "/a/b",
+		"/api/b2b/upload/./": "/api/b2b/upload/",
+	}
+	for in, want := range tests {
+		if got := cleanPath(in); got != want {
+			t.Errorf("cleanPath(%q) = %q, seharusnya %q", in, got, want)
+		}
+	}
+}
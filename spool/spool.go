@@ -0,0 +1,552 @@
+package spool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/luhtaf/corator/config"
+	"github.com/luhtaf/corator/evidence"
+	"github.com/luhtaf/corator/logger"
+	"github.com/luhtaf/corator/metrics"
+	"github.com/luhtaf/corator/uploader"
+)
+
+const (
+	uploadsDir = "uploads"
+	eventsDir  = "events"
+	corruptDir = "corrupt" // Entri yang tidak bisa dibaca, disimpan untuk diperiksa manual
+)
+
+// uploadEntry adalah upload yang tertunda. Kontennya disimpan di file "<id>.bin"
+// di samping file entri "<id>.json".
+type uploadEntry struct {
+	Uploader       string            `json:"uploader,omitempty"`
+	UniqueFilename string            `json:"unique_filename"`
+	Metadata       evidence.Metadata `json:"metadata"`
+	Attempts       int               `json:"attempts"`
+	NextAttempt    time.Time         `json:"next_attempt"`
+	LastError      string            `json:"last_error"`
+}
+
+// eventEntry adalah log event yang tertunda untuk satu logger tertentu.
+type eventEntry struct {
+	Logger      string          `json:"logger"`
+	Event       logger.LogEvent `json:"event"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt time.Time       `json:"next_attempt"`
+	LastError   string          `json:"last_error"`
+}
+
+// Spool adalah antrean tulis-dulu (write-ahead) di disk untuk file bukti dan
+// log event yang gagal dikirim. Entri dicoba ulang dengan exponential backoff
+// dan tetap ada setelah restart, sehingga gangguan S3 atau Elasticsearch tidak
+// menimbulkan celah forensik.
+type Spool struct {
+	dir           string
+	retryInterval time.Duration
+	minBackoff    time.Duration
+	maxBackoff    time.Duration
+
+	// targetsMu melindungi uploaders dan loggers yang bisa diganti saat reload.
+	targetsMu sync.RWMutex
+	uploaders []uploader.Uploader
+	loggers   []logger.Logger
+
+	// mu memastikan hanya satu proses retry yang berjalan pada satu waktu.
+	mu           sync.Mutex
+	uploadsDepth atomic.Int64
+	eventsDepth  atomic.Int64
+}
+
+// New membuat Spool baru dan menghitung entri yang tersisa dari proses sebelumnya.
+// Uploader dan logger pertama adalah default, dipakai untuk entri yang uploader atau
+// logger-nya tidak lagi aktif.
+func New(cfg config.SpoolConfig, uploaders []uploader.Uploader, loggers []logger.Logger) (*Spool, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("path spool tidak boleh kosong")
+	}
+	if len(uploaders) == 0 {
+		return nil, fmt.Errorf("spool membutuhkan minimal satu uploader")
+	}
+
+	for _, sub := range []string{uploadsDir, eventsDir} {
+		if err := os.MkdirAll(filepath.Join(cfg.Path, sub), 0755); err != nil {
+			return nil, fmt.Errorf("gagal membuat direktori spool: %w", err)
+		}
+	}
+
+	s := &Spool{
+		dir:           cfg.Path,
+		retryInterval: cfg.RetryInterval,
+		minBackoff:    cfg.MinBackoff,
+		maxBackoff:    cfg.MaxBackoff,
+		uploaders:     uploaders,
+		loggers:       loggers,
+	}
+
+	if err := s.recoverOrphans(); err != nil {
+		return nil, err
+	}
+
+	uploads, err := s.entries(uploadsDir)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.entries(eventsDir)
+	if err != nil {
+		return nil, err
+	}
+	s.uploadsDepth.Store(int64(len(uploads)))
+	s.eventsDepth.Store(int64(len(events)))
+
+	return s, nil
+}
+
+// SetTargets mengganti uploader dan logger tujuan setelah konfigurasi di-reload.
+// Entri yang sudah ada di spool dikirim ulang ke target baru dengan nama yang sama.
+func (s *Spool) SetTargets(uploaders []uploader.Uploader, loggers []logger.Logger) error {
+	if len(uploaders) == 0 {
+		return fmt.Errorf("spool membutuhkan minimal satu uploader")
+	}
+	s.targetsMu.Lock()
+	defer s.targetsMu.Unlock()
+	s.uploaders = uploaders
+	s.loggers = loggers
+	return nil
+}
+
+// Depth mengembalikan jumlah upload dan log event yang masih tertunda.
+func (s *Spool) Depth() (uploads, events int64) {
+	return s.uploadsDepth.Load(), s.eventsDepth.Load()
+}
+
+// EnqueueUpload menyimpan file bukti yang gagal diunggah agar dicoba lagi nanti
+// lewat uploader dengan nama uploaderName.
+func (s *Spool) EnqueueUpload(content io.Reader, uploaderName, uniqueFilename string, meta evidence.Metadata, cause error) error {
+	id := newEntryID()
+	base := filepath.Join(s.dir, uploadsDir, id)
+
+	// Tulis konten lebih dulu; file .json menandai entri sudah lengkap.
+	if err := writeContent(base+".bin", content); err != nil {
+		return err
+	}
+
+	entry := uploadEntry{
+		Uploader:       uploaderName,
+		UniqueFilename: uniqueFilename,
+		Metadata:       meta,
+		Attempts:       1,
+		NextAttempt:    time.Now().Add(s.backoff(1)),
+		LastError:      cause.Error(),
+	}
+	if err := writeJSON(base+".json", entry); err != nil {
+		os.Remove(base + ".bin")
+		return err
+	}
+
+	s.uploadsDepth.Add(1)
+	return nil
+}
+
+// EnqueueEvent menyimpan log event yang gagal dicatat oleh logger tertentu.
+func (s *Spool) EnqueueEvent(loggerName string, event logger.LogEvent, cause error) error {
+	entry := eventEntry{
+		Logger:      loggerName,
+		Event:       event,
+		Attempts:    1,
+		NextAttempt: time.Now().Add(s.backoff(1)),
+		LastError:   cause.Error(),
+	}
+	if err := writeJSON(filepath.Join(s.dir, eventsDir, newEntryID()+".json"), entry); err != nil {
+		return err
+	}
+
+	s.eventsDepth.Add(1)
+	return nil
+}
+
+// Deliver mengirim event ke semua logger. Logger yang gagal, termasuk karena ctx
+// habis, akan menerima event tersebut lagi lewat spool.
+func (s *Spool) Deliver(ctx context.Context, event logger.LogEvent) {
+	s.targetsMu.RLock()
+	loggers := s.loggers
+	s.targetsMu.RUnlock()
+
+	for _, l := range loggers {
+		if err := l.Log(ctx, event); err != nil {
+			metrics.ObserveLoggerFailure(l.Name())
+			log.Printf("[%s] Logger %s gagal, event disimpan ke spool: %v", event.RequestID, l.Name(), err)
+			if err := s.EnqueueEvent(l.Name(), event, err); err != nil {
+				log.Printf("[%s] PERINGATAN: Gagal menyimpan event ke spool, event hilang: %v", event.RequestID, err)
+			}
+		}
+	}
+}
+
+// Run menjalankan proses retry secara berkala hingga context dibatalkan.
+func (s *Spool) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.retryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Retry(ctx)
+		}
+	}
+}
+
+// Retry mencoba ulang semua entri yang sudah waktunya dikirim.
+func (s *Spool) Retry(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.retryUploads(ctx)
+	s.retryEvents(ctx)
+
+	if uploads, events := s.Depth(); uploads > 0 || events > 0 {
+		log.Printf("Spool: %d upload dan %d log event masih tertunda", uploads, events)
+	}
+}
+
+// retryUploads mencoba ulang upload yang tertunda, lalu mengirim log event-nya.
+func (s *Spool) retryUploads(ctx context.Context) {
+	ids, err := s.entries(uploadsDir)
+	if err != nil {
+		log.Printf("Spool: %v", err)
+		return
+	}
+
+	for _, id := range ids {
+		base := filepath.Join(s.dir, uploadsDir, id)
+
+		var entry uploadEntry
+		if err := readJSON(base+".json", &entry); err != nil {
+			log.Printf("Spool: Gagal membaca entri upload %s: %v", id, err)
+			if isCorrupt(err) {
+				s.quarantine(uploadsDir, base+".json", base+".bin")
+				s.uploadsDepth.Add(-1)
+			}
+			continue
+		}
+		if time.Now().Before(entry.NextAttempt) {
+			continue
+		}
+
+		uploadPath, err := s.upload(ctx, base+".bin", entry)
+		if err != nil {
+			entry.Attempts++
+			entry.NextAttempt = time.Now().Add(s.backoff(entry.Attempts))
+			entry.LastError = err.Error()
+			if err := writeJSON(base+".json", entry); err != nil {
+				log.Printf("Spool: Gagal memperbarui entri upload %s: %v", id, err)
+			}
+			continue
+		}
+
+		os.Remove(base + ".json")
+		os.Remove(base + ".bin")
+		s.uploadsDepth.Add(-1)
+
+		log.Printf("[%s] Spool: File %s berhasil diunggah setelah %d percobaan: %s", entry.Metadata.RequestID, entry.Metadata.OriginalName, entry.Attempts+1, uploadPath)
+		s.Deliver(ctx, logger.NewLogEvent(entry.Metadata, uploadPath))
+	}
+}
+
+// upload mengunggah konten entri dari file spool.
+func (s *Spool) upload(ctx context.Context, path string, entry uploadEntry) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	up := s.uploader(entry.Uploader)
+	started := time.Now()
+	uploadPath, err := up.Upload(ctx, file, entry.UniqueFilename, entry.Metadata)
+	metrics.ObserveUpload(up.Name(), started, err)
+	return uploadPath, err
+}
+
+// uploader mencari uploader aktif berdasarkan nama, atau uploader default jika tidak ada.
+func (s *Spool) uploader(name string) uploader.Uploader {
+	s.targetsMu.RLock()
+	defer s.targetsMu.RUnlock()
+	for _, up := range s.uploaders {
+		if up.Name() == name {
+			return up
+		}
+	}
+	return s.uploaders[0]
+}
+
+// retryEvents mencoba ulang log event yang tertunda ke logger asalnya, atau ke logger
+// default jika logger asalnya tidak lagi aktif.
+func (s *Spool) retryEvents(ctx context.Context) {
+	ids, err := s.entries(eventsDir)
+	if err != nil {
+		log.Printf("Spool: %v", err)
+		return
+	}
+
+	for _, id := range ids {
+		path := filepath.Join(s.dir, eventsDir, id+".json")
+
+		var entry eventEntry
+		if err := readJSON(path, &entry); err != nil {
+			log.Printf("Spool: Gagal membaca entri event %s: %v", id, err)
+			if isCorrupt(err) {
+				s.quarantine(eventsDir, path)
+				s.eventsDepth.Add(-1)
+			}
+			continue
+		}
+		if time.Now().Before(entry.NextAttempt) {
+			continue
+		}
+
+		// Tanpa logger aktif sama sekali, event tetap disimpan sampai ada logger lagi
+		l := s.logger(entry.Logger)
+		if l == nil {
+			continue
+		}
+
+		if err := l.Log(ctx, entry.Event); err != nil {
+			metrics.ObserveLoggerFailure(l.Name())
+			entry.Attempts++
+			entry.NextAttempt = time.Now().Add(s.backoff(entry.Attempts))
+			entry.LastError = err.Error()
+			if err := writeJSON(path, entry); err != nil {
+				log.Printf("Spool: Gagal memperbarui entri event %s: %v", id, err)
+			}
+			continue
+		}
+
+		os.Remove(path)
+		s.eventsDepth.Add(-1)
+	}
+}
+
+// logger mencari logger aktif berdasarkan nama, atau logger default jika tidak ada.
+// Mengembalikan nil jika tidak ada logger yang aktif.
+func (s *Spool) logger(name string) logger.Logger {
+	s.targetsMu.RLock()
+	defer s.targetsMu.RUnlock()
+	for _, l := range s.loggers {
+		if l.Name() == name {
+			return l
+		}
+	}
+	if len(s.loggers) == 0 {
+		return nil
+	}
+	return s.loggers[0]
+}
+
+// recoverOrphans memulihkan konten upload yang file entrinya tidak sempat ditulis,
+// misalnya karena proses berhenti di tengah EnqueueUpload. Konten tersebut tetap
+// diunggah lewat uploader default dengan metadata yang bisa direkonstruksi: ukuran,
+// hash dan waktu file spool dibuat. File entri sementara yang tertinggal dibuang,
+// dan entri yang rusak (misalnya terpotong karena disk penuh) dipindahkan ke
+// direktori corrupt sehingga kontennya ikut dipulihkan sebagai orphan.
+func (s *Spool) recoverOrphans() error {
+	for _, sub := range []string{uploadsDir, eventsDir} {
+		tmps, _ := filepath.Glob(filepath.Join(s.dir, sub, "*.json.tmp"))
+		for _, tmp := range tmps {
+			os.Remove(tmp)
+		}
+
+		ids, err := s.entries(sub)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			path := filepath.Join(s.dir, sub, id+".json")
+			var entry any = &eventEntry{}
+			if sub == uploadsDir {
+				entry = &uploadEntry{}
+			}
+			if err := readJSON(path, entry); isCorrupt(err) {
+				log.Printf("PERINGATAN: Spool: Entri %s/%s rusak: %v", sub, id, err)
+				s.quarantine(sub, path)
+			}
+		}
+	}
+
+	bins, err := filepath.Glob(filepath.Join(s.dir, uploadsDir, "*.bin"))
+	if err != nil {
+		return fmt.Errorf("gagal membaca direktori spool: %w", err)
+	}
+	for _, bin := range bins {
+		base := strings.TrimSuffix(bin, ".bin")
+		if _, err := os.Stat(base + ".json"); !os.IsNotExist(err) {
+			continue
+		}
+
+		meta, err := orphanMetadata(bin)
+		if err != nil {
+			log.Printf("PERINGATAN: Spool: Gagal membaca konten upload tanpa entri %s: %v", bin, err)
+			continue
+		}
+		id := filepath.Base(base)
+		entry := uploadEntry{
+			UniqueFilename: "spool-recovered_" + id + ".bin",
+			Metadata:       meta,
+			NextAttempt:    time.Now(),
+			LastError:      "entri upload tidak ditemukan, dipulihkan saat startup",
+		}
+		if err := writeJSON(base+".json", entry); err != nil {
+			return err
+		}
+		log.Printf("PERINGATAN: Spool: Konten upload %s tanpa metadata dipulihkan (sha256 %s)", id, meta.SHA256)
+	}
+	return nil
+}
+
+// quarantine memindahkan file entri yang rusak ke direktori corrupt agar tidak dibaca
+// ulang di setiap retry. Jika gagal dipindahkan, file dihapus.
+func (s *Spool) quarantine(sub string, paths ...string) {
+	dir := filepath.Join(s.dir, corruptDir, sub)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Printf("PERINGATAN: Spool: Gagal membuat direktori entri rusak: %v", err)
+	}
+	for _, path := range paths {
+		dst := filepath.Join(dir, filepath.Base(path))
+		if err := os.Rename(path, dst); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			log.Printf("PERINGATAN: Spool: Gagal memindahkan entri rusak %s, file dihapus: %v", path, err)
+			os.Remove(path)
+			continue
+		}
+		log.Printf("PERINGATAN: Spool: Entri rusak %s dipindahkan ke %s", filepath.Base(path), dst)
+	}
+}
+
+// isCorrupt mengembalikan true jika err berasal dari isi entri yang tidak valid, bukan
+// dari kegagalan membaca file.
+func isCorrupt(err error) bool {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	return errors.As(err, &syntaxErr) || errors.As(err, &typeErr)
+}
+
+// orphanMetadata menyusun metadata untuk konten upload yang entrinya hilang.
+func orphanMetadata(path string) (evidence.Metadata, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return evidence.Metadata{}, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return evidence.Metadata{}, err
+	}
+	hashes, err := evidence.HashReader(file)
+	if err != nil {
+		return evidence.Metadata{}, err
+	}
+	return evidence.Metadata{
+		Hashes:       hashes,
+		OriginalName: filepath.Base(path),
+		Size:         info.Size(),
+		CapturedAt:   info.ModTime(),
+	}, nil
+}
+
+// backoff menghitung jeda sebelum percobaan berikutnya: minBackoff * 2^(attempts-1),
+// dibatasi maxBackoff.
+func (s *Spool) backoff(attempts int) time.Duration {
+	d := s.minBackoff
+	for i := 1; i < attempts && d < s.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > s.maxBackoff {
+		d = s.maxBackoff
+	}
+	return d
+}
+
+// entries mengembalikan ID entri lengkap (yang punya file .json) di sub-direktori, urut dari yang tertua.
+func (s *Spool) entries(sub string) ([]string, error) {
+	files, err := os.ReadDir(filepath.Join(s.dir, sub))
+	if err != nil {
+		return nil, fmt.Errorf("gagal membaca direktori spool: %w", err)
+	}
+
+	var ids []string
+	for _, f := range files {
+		if id, ok := strings.CutSuffix(f.Name(), ".json"); ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// newEntryID membuat ID entri yang urut berdasarkan waktu pembuatan.
+func newEntryID() string {
+	return fmt.Sprintf("%020d_%s", time.Now().UnixNano(), uuid.New().String())
+}
+
+// writeContent menyalin konten ke file dan memastikan datanya sudah tersimpan di disk.
+func writeContent(path string, content io.Reader) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("gagal membuat file spool: %w", err)
+	}
+	if _, err := io.Copy(file, content); err != nil {
+		file.Close()
+		os.Remove(path)
+		return fmt.Errorf("gagal menulis file spool: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(path)
+		return fmt.Errorf("gagal menyimpan file spool: %w", err)
+	}
+	return file.Close()
+}
+
+// writeJSON menulis entri secara atomik lewat file sementara dan rename.
+func writeJSON(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("gagal marshal entri spool: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("gagal menulis entri spool: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("gagal menyimpan entri spool: %w", err)
+	}
+	return nil
+}
+
+// readJSON membaca satu entri spool.
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
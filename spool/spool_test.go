@@ -0,0 +1,188 @@
+package spool
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luhtaf/corator/config"
+	"github.com/luhtaf/corator/evidence"
+	"github.com/luhtaf/corator/logger"
+	"github.com/luhtaf/corator/uploader"
+)
+
+// memoryLogger menyimpan event yang dicatat di memori.
+type memoryLogger struct {
+	name   string
+	events []logger.LogEvent
+}
+
+func (l *memoryLogger) Name() string                    { return l.name }
+func (l *memoryLogger) Check(ctx context.Context) error { return nil }
+func (l *memoryLogger) Close() error                    { return nil }
+func (l *memoryLogger) Log(ctx context.Context, event logger.LogEvent) error {
+	l.events = append(l.events, event)
+	return nil
+}
+
+func newTestSpool(t *testing.T, dir string, loggers ...logger.Logger) *Spool {
+	t.Helper()
+	up, err := uploader.NewLocalUploader(config.LocalConfig{Path: filepath.Join(dir, "evidence")})
+	if err != nil {
+		t.Fatalf("NewLocalUploader: %v", err)
+	}
+	s, err := New(config.SpoolConfig{Path: filepath.Join(dir, "spool"), RetryInterval: time.Minute, MinBackoff: 0, MaxBackoff: 0}, []uploader.Uploader{up}, loggers)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return s
+}
+
+func TestRetryEventsFallsBackToDefaultLogger(t *testing.T) {
+	dir := t.TempDir()
+	s := newTestSpool(t, dir)
+
+	// Event untuk logger yang tidak lagi aktif tetap disimpan selama belum ada logger
+	if err := s.EnqueueEvent("elastic", logger.LogEvent{RequestID: "req-1"}, errors.New("timeout")); err != nil {
+		t.Fatalf("EnqueueEvent: %v", err)
+	}
+	s.Retry(context.Background())
+	if _, events := s.Depth(); events != 1 {
+		t.Fatalf("event dibuang tanpa logger aktif, sisa %d", events)
+	}
+
+	file := &memoryLogger{name: "file"}
+	if err := s.SetTargets(s.uploaders, []logger.Logger{file}); err != nil {
+		t.Fatalf("SetTargets: %v", err)
+	}
+	s.Retry(context.Background())
+
+	if len(file.events) != 1 || file.events[0].RequestID != "req-1" {
+		t.Errorf("event tidak dikirim ke logger default: %+v", file.events)
+	}
+	if _, events := s.Depth(); events != 0 {
+		t.Errorf("event masih tertunda: %d", events)
+	}
+}
+
+func TestNewRecoversOrphanedUpload(t *testing.T) {
+	dir := t.TempDir()
+	uploads := filepath.Join(dir, "spool", uploadsDir)
+	if err := os.MkdirAll(uploads, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Proses sebelumnya berhenti setelah konten ditulis tetapi sebelum entrinya ditulis
+	if err := os.WriteFile(filepath.Join(uploads, "00000000000000000001_orphan.bin"), []byte("bukti"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(uploads, "00000000000000000002_partial.json.tmp"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	file := &memoryLogger{name: "file"}
+	s := newTestSpool(t, dir, file)
+	if uploadsDepth, _ := s.Depth(); uploadsDepth != 1 {
+		t.Fatalf("upload tertunda = %d, seharusnya 1", uploadsDepth)
+	}
+	if _, err := os.Stat(filepath.Join(uploads, "00000000000000000002_partial.json.tmp")); !os.IsNotExist(err) {
+		t.Errorf("file entri sementara tidak dibuang")
+	}
+
+	s.Retry(context.Background())
+
+	if uploadsDepth, _ := s.Depth(); uploadsDepth != 0 {
+		t.Fatalf("upload yang dipulihkan belum terkirim, sisa %d", uploadsDepth)
+	}
+	if len(file.events) != 1 {
+		t.Fatalf("jumlah event = %d, seharusnya 1", len(file.events))
+	}
+	event := file.events[0]
+	want, _ := evidence.HashReader(strings.NewReader("bukti"))
+	if event.SHA256 != want.SHA256 || event.FileSize != 5 {
+		t.Errorf("metadata upload yang dipulihkan tidak sesuai: sha256 %s, ukuran %d", event.SHA256, event.FileSize)
+	}
+	if _, err := os.Stat(event.UploadPath); err != nil {
+		t.Errorf("file bukti yang dipulihkan tidak diunggah: %v", err)
+	}
+}
+
+func TestNewQuarantinesCorruptEntries(t *testing.T) {
+	dir := t.TempDir()
+	spoolDir := filepath.Join(dir, "spool")
+	files := map[string]string{
+		// Entri upload terpotong di tengah, kontennya masih utuh
+		filepath.Join(uploadsDir, "00000000000000000001_a.json"): `{"unique_filename":"req_a.bin","metadata":{`,
+		filepath.Join(uploadsDir, "00000000000000000001_a.bin"):  "bukti",
+		// Entri event dengan tipe field yang salah
+		filepath.Join(eventsDir, "00000000000000000002_b.json"): `{"logger":"file","attempts":"tiga"}`,
+		filepath.Join(eventsDir, "00000000000000000003_c.json"): "",
+	}
+	for name, data := range files {
+		path := filepath.Join(spoolDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	file := &memoryLogger{name: "file"}
+	s := newTestSpool(t, dir, file)
+
+	for _, name := range []string{
+		filepath.Join(uploadsDir, "00000000000000000001_a.json"),
+		filepath.Join(eventsDir, "00000000000000000002_b.json"),
+		filepath.Join(eventsDir, "00000000000000000003_c.json"),
+	} {
+		if _, err := os.Stat(filepath.Join(spoolDir, corruptDir, name)); err != nil {
+			t.Errorf("entri rusak %s tidak dipindahkan ke %s: %v", name, corruptDir, err)
+		}
+	}
+	uploads, events := s.Depth()
+	if uploads != 1 || events != 0 {
+		t.Fatalf("tertunda %d upload dan %d event, seharusnya 1 upload (konten dipulihkan) dan 0 event", uploads, events)
+	}
+
+	// Konten dari entri upload yang rusak tetap diunggah
+	s.Retry(context.Background())
+	if uploads, _ := s.Depth(); uploads != 0 {
+		t.Fatalf("upload yang dipulihkan belum terkirim, sisa %d", uploads)
+	}
+	want, _ := evidence.HashReader(strings.NewReader("bukti"))
+	if len(file.events) != 1 || file.events[0].SHA256 != want.SHA256 {
+		t.Errorf("event upload yang dipulihkan = %+v", file.events)
+	}
+}
+
+func TestRetryQuarantinesCorruptEntry(t *testing.T) {
+	dir := t.TempDir()
+	s := newTestSpool(t, dir)
+
+	if err := s.EnqueueEvent("file", logger.LogEvent{RequestID: "req-1"}, errors.New("timeout")); err != nil {
+		t.Fatalf("EnqueueEvent: %v", err)
+	}
+	paths, _ := filepath.Glob(filepath.Join(dir, "spool", eventsDir, "*.json"))
+	if len(paths) != 1 {
+		t.Fatalf("jumlah entri event = %d, seharusnya 1", len(paths))
+	}
+	if err := os.WriteFile(paths[0], []byte(`{"logger":"fi`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Retry(context.Background())
+
+	if _, events := s.Depth(); events != 0 {
+		t.Errorf("entri rusak masih dihitung tertunda: %d", events)
+	}
+	if _, err := os.Stat(paths[0]); !os.IsNotExist(err) {
+		t.Errorf("entri rusak masih ada di antrean")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "spool", corruptDir, eventsDir, filepath.Base(paths[0]))); err != nil {
+		t.Errorf("entri rusak tidak dipindahkan ke %s: %v", corruptDir, err)
+	}
+}
@@ -0,0 +1,244 @@
+package tlsserver
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/luhtaf/corator/config"
+	"github.com/luhtaf/corator/metrics"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// versions memetakan nilai TLS_MIN_VERSION dan TLS_MAX_VERSION ke konstanta crypto/tls.
+var versions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// clientAuthModes memetakan nilai TLS_CLIENT_AUTH ke mode verifikasi sertifikat client.
+var clientAuthModes = map[string]tls.ClientAuthType{
+	"none":    tls.NoClientCert,
+	"request": tls.VerifyClientCertIfGiven,
+	"require": tls.RequireAndVerifyClientCert,
+}
+
+// Manager menyimpan sertifikat listener HTTPS, memilihnya berdasarkan SNI dan memuatnya
+// ulang saat file berubah tanpa memutus koneksi yang sedang berjalan.
+type Manager struct {
+	cfg       config.TLSConfig
+	tlsConfig *tls.Config
+	certs     atomic.Pointer[[]*tls.Certificate] // Urutan konfigurasi, elemen pertama adalah default
+	clientCAs atomic.Pointer[x509.CertPool]      // nil jika sertifikat client tidak diverifikasi
+	acme      *autocert.Manager                  // nil jika ACME dinonaktifkan
+}
+
+// New memuat semua sertifikat dan menyusun kebijakan TLS dari konfigurasi.
+func New(cfg config.TLSConfig) (*Manager, error) {
+	m := &Manager{cfg: cfg}
+	if err := m.Reload(); err != nil {
+		return nil, err
+	}
+
+	m.tlsConfig = &tls.Config{
+		GetCertificate: m.getCertificate,
+		MinVersion:     versions[cfg.MinVersion],
+		MaxVersion:     versions[cfg.MaxVersion],
+		NextProtos:     []string{"h2", "http/1.1"},
+		ClientAuth:     clientAuthModes[cfg.ClientAuth],
+	}
+	if cfg.ClientCAFile != "" {
+		// CA bundle dibaca per handshake agar perubahan file berlaku tanpa restart
+		m.tlsConfig.GetConfigForClient = m.configForClient
+	}
+	for _, name := range cfg.CipherSuites {
+		idx := slices.IndexFunc(tls.CipherSuites(), func(cs *tls.CipherSuite) bool { return cs.Name == name })
+		if idx < 0 {
+			return nil, fmt.Errorf("cipher suite %q tidak dikenal atau tidak aman", name)
+		}
+		m.tlsConfig.CipherSuites = append(m.tlsConfig.CipherSuites, tls.CipherSuites()[idx].ID)
+	}
+
+	if cfg.ACME.Enable {
+		var err error
+		if m.acme, err = newACME(cfg.ACME); err != nil {
+			return nil, err
+		}
+		// Challenge TLS-ALPN-01 dijawab langsung di listener HTTPS
+		m.tlsConfig.NextProtos = append(m.tlsConfig.NextProtos, acme.ALPNProto)
+	}
+	return m, nil
+}
+
+// TLSConfig mengembalikan konfigurasi untuk http.Server listener HTTPS.
+func (m *Manager) TLSConfig() *tls.Config {
+	return m.tlsConfig
+}
+
+// HTTPHandler membungkus handler listener HTTP agar menjawab challenge ACME HTTP-01.
+// Jika ACME dinonaktifkan, handler dikembalikan apa adanya.
+func (m *Manager) HTTPHandler(h http.Handler) http.Handler {
+	if m.acme == nil {
+		return h
+	}
+	return m.acme.HTTPHandler(h)
+}
+
+// Reload memuat ulang semua file sertifikat dan CA bundle client. Jika ada yang gagal
+// dimuat, sertifikat dan CA lama tetap dipakai.
+func (m *Manager) Reload() error {
+	var clientCAs *x509.CertPool
+	if m.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(m.cfg.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("gagal membaca CA client %s: %w", m.cfg.ClientCAFile, err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("file CA client %s tidak berisi sertifikat PEM", m.cfg.ClientCAFile)
+		}
+	}
+
+	var certs []*tls.Certificate
+	expiries := make(map[string]time.Time)
+	for _, file := range m.files() {
+		cert, err := tls.LoadX509KeyPair(file.CertFile, file.KeyFile)
+		if err != nil {
+			return fmt.Errorf("gagal memuat sertifikat %s: %w", file.CertFile, err)
+		}
+		if time.Now().After(cert.Leaf.NotAfter) {
+			log.Printf("PERINGATAN: Sertifikat %s sudah kedaluwarsa sejak %s", file.CertFile, cert.Leaf.NotAfter.Format(time.RFC3339))
+		}
+		expiries[file.CertFile] = cert.Leaf.NotAfter
+		certs = append(certs, &cert)
+	}
+	m.certs.Store(&certs)
+	m.clientCAs.Store(clientCAs)
+	metrics.SetCertificateExpiries(expiries)
+	return nil
+}
+
+// Run memuat ulang sertifikat setiap kali menerima SIGHUP dan, jika ReloadInterval diisi,
+// setiap kali file sertifikat, private key atau CA bundle client berubah.
+func (m *Manager) Run(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	if m.cfg.ReloadInterval > 0 {
+		ticker := time.NewTicker(m.cfg.ReloadInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	lastSeen := m.fingerprint()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		case <-tick:
+			if m.fingerprint() == lastSeen {
+				continue
+			}
+		}
+
+		if err := m.Reload(); err != nil {
+			log.Printf("PERINGATAN: Reload sertifikat gagal, sertifikat lama tetap dipakai: %v", err)
+		} else {
+			log.Println("Sertifikat TLS berhasil dimuat ulang.")
+		}
+		// File yang gagal dimuat tidak dicoba lagi sampai berubah kembali
+		lastSeen = m.fingerprint()
+	}
+}
+
+// getCertificate memilih sertifikat untuk satu handshake. Domain ACME dan challenge
+// TLS-ALPN-01 dilayani oleh ACME; selain itu sertifikat pertama yang cocok dengan SNI
+// dan kemampuan client dipakai, atau sertifikat default jika tidak ada yang cocok.
+func (m *Manager) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if m.acme != nil && (slices.Contains(hello.SupportedProtos, acme.ALPNProto) || m.isACMEDomain(hello.ServerName)) {
+		return m.acme.GetCertificate(hello)
+	}
+
+	certs := *m.certs.Load()
+	for _, cert := range certs {
+		if hello.SupportsCertificate(cert) == nil {
+			return cert, nil
+		}
+	}
+	if len(certs) > 0 {
+		return certs[0], nil
+	}
+	if m.acme != nil {
+		return m.acme.GetCertificate(hello)
+	}
+	return nil, fmt.Errorf("tidak ada sertifikat untuk %q", hello.ServerName)
+}
+
+// configForClient menyalin konfigurasi TLS dengan CA bundle client yang terbaru. Challenge
+// TLS-ALPN-01 tidak membawa sertifikat client, jadi verifikasi client dilewati untuknya.
+func (m *Manager) configForClient(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+	cfg := m.tlsConfig.Clone()
+	cfg.GetConfigForClient = nil
+	cfg.ClientCAs = m.clientCAs.Load()
+	if slices.Contains(hello.SupportedProtos, acme.ALPNProto) {
+		cfg.ClientAuth = tls.NoClientCert
+	}
+	return cfg, nil
+}
+
+// isACMEDomain mengembalikan true jika nama SNI termasuk TLS_ACME_DOMAINS.
+func (m *Manager) isACMEDomain(serverName string) bool {
+	name := strings.TrimSuffix(serverName, ".")
+	return slices.ContainsFunc(m.cfg.ACME.Domains, func(domain string) bool {
+		return strings.EqualFold(domain, name)
+	})
+}
+
+// files mengembalikan sertifikat default diikuti sertifikat tambahan.
+func (m *Manager) files() []config.CertificateConfig {
+	var files []config.CertificateConfig
+	if m.cfg.CertFile != "" {
+		files = append(files, config.CertificateConfig{CertFile: m.cfg.CertFile, KeyFile: m.cfg.KeyFile})
+	}
+	return append(files, m.cfg.Certificates...)
+}
+
+// fingerprint merangkum ukuran dan waktu modifikasi semua file sertifikat, private key
+// dan CA bundle client.
+// os.Stat mengikuti symlink, sehingga pergantian Secret Kubernetes juga terdeteksi.
+func (m *Manager) fingerprint() string {
+	paths := []string{m.cfg.ClientCAFile}
+	for _, file := range m.files() {
+		paths = append(paths, file.CertFile, file.KeyFile)
+	}
+
+	var sb strings.Builder
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintf(&sb, "%s:-;", path)
+			continue
+		}
+		fmt.Fprintf(&sb, "%s:%d:%d;", path, info.Size(), info.ModTime().UnixNano())
+	}
+	return sb.String()
+}
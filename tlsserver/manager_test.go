@@ -0,0 +1,215 @@
+package tlsserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/luhtaf/corator/config"
+)
+
+// ecdsaHello adalah ClientHello minimal dari client yang mendukung sertifikat ECDSA.
+func ecdsaHello(serverName string) *tls.ClientHelloInfo {
+	return &tls.ClientHelloInfo{
+		ServerName:        serverName,
+		CipherSuites:      []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+		SignatureSchemes:  []tls.SignatureScheme{tls.ECDSAWithP256AndSHA256},
+		SupportedCurves:   []tls.CurveID{tls.CurveP256},
+		SupportedVersions: []uint16{tls.VersionTLS13, tls.VersionTLS12},
+	}
+}
+
+// writeCert menulis sertifikat self-signed untuk names beserta private key-nya ke dir
+// dan mengembalikan path kedua file.
+func writeCert(t *testing.T, dir, name string, names ...string) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: names[0]},
+		DNSNames:     names,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey: %v", err)
+	}
+
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// servedName mengembalikan nama DNS pertama sertifikat yang dipilih untuk hello.
+func servedName(t *testing.T, m *Manager, hello *tls.ClientHelloInfo) string {
+	t.Helper()
+	cert, err := m.TLSConfig().GetCertificate(hello)
+	if err != nil {
+		t.Fatalf("GetCertificate(%q): %v", hello.ServerName, err)
+	}
+	return cert.Leaf.DNSNames[0]
+}
+
+func TestGetCertificateBySNI(t *testing.T) {
+	dir := t.TempDir()
+	defaultCert, defaultKey := writeCert(t, dir, "default", "www.corator.test")
+	apiCert, apiKey := writeCert(t, dir, "api", "api.corator.test", "*.api.corator.test")
+
+	m, err := New(config.TLSConfig{
+		CertFile:     defaultCert,
+		KeyFile:      defaultKey,
+		Certificates: []config.CertificateConfig{{CertFile: apiCert, KeyFile: apiKey}},
+		MinVersion:   "1.2",
+		ClientAuth:   "none",
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	tests := map[string]string{
+		"api.corator.test":    "api.corator.test",
+		"v2.api.corator.test": "api.corator.test",
+		"www.corator.test":    "www.corator.test",
+		"unknown.test":        "www.corator.test", // Sertifikat default
+		"":                    "www.corator.test",
+	}
+	for sni, want := range tests {
+		if got := servedName(t, m, ecdsaHello(sni)); got != want {
+			t.Errorf("SNI %q mendapat sertifikat %s, seharusnya %s", sni, got, want)
+		}
+	}
+}
+
+func TestReloadKeepsCertificateOnError(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir, "site", "www.corator.test")
+
+	m, err := New(config.TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientAuth: "none"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	// Sertifikat baru dengan private key yang rusak tidak boleh menggantikan yang lama
+	writeCert(t, dir, "site", "new.corator.test")
+	if err := os.WriteFile(keyFile, []byte("bukan private key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Reload(); err == nil {
+		t.Fatal("Reload seharusnya gagal dengan private key yang rusak")
+	}
+	if got := servedName(t, m, ecdsaHello("www.corator.test")); got != "www.corator.test" {
+		t.Errorf("sertifikat lama diganti menjadi %s setelah reload gagal", got)
+	}
+
+	// Setelah pasangan sertifikat dan key kembali valid, reload memakai sertifikat baru
+	writeCert(t, dir, "site", "new.corator.test")
+	if err := m.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if got := servedName(t, m, ecdsaHello("new.corator.test")); got != "new.corator.test" {
+		t.Errorf("sertifikat setelah reload = %s, seharusnya new.corator.test", got)
+	}
+}
+
+func TestACMEDomainServedByACME(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir, "static", "www.corator.test")
+
+	// Sertifikat ACME yang sudah ada di cache dipakai tanpa menghubungi server ACME
+	cacheDir := filepath.Join(dir, "acme")
+	if err := os.Mkdir(cacheDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	acmeCert, acmeKey := writeCert(t, dir, "acme-issued", "shop.corator.test")
+	certPEM, _ := os.ReadFile(acmeCert)
+	keyPEM, _ := os.ReadFile(acmeKey)
+	if err := os.WriteFile(filepath.Join(cacheDir, "shop.corator.test"), append(keyPEM, certPEM...), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := New(config.TLSConfig{
+		CertFile:   certFile,
+		KeyFile:    keyFile,
+		ClientAuth: "none",
+		ACME: config.ACMEConfig{
+			Enable:       true,
+			Domains:      []string{"shop.corator.test"},
+			DirectoryURL: "http://127.0.0.1:1/directory", // Tidak boleh dihubungi
+			CacheDir:     cacheDir,
+		},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	if !slices.Contains(m.TLSConfig().NextProtos, "acme-tls/1") {
+		t.Errorf("NextProtos %v tidak mengiklankan challenge TLS-ALPN-01", m.TLSConfig().NextProtos)
+	}
+	if got := servedName(t, m, ecdsaHello("SHOP.corator.test")); got != "shop.corator.test" {
+		t.Errorf("domain ACME mendapat sertifikat %s, seharusnya dari ACME", got)
+	}
+	if got := servedName(t, m, ecdsaHello("www.corator.test")); got != "www.corator.test" {
+		t.Errorf("domain non-ACME mendapat sertifikat %s, seharusnya sertifikat statis", got)
+	}
+}
+
+// TestACMEIssuanceWithPebble menerbitkan sertifikat dari server ACME pebble. Test ini
+// hanya berjalan jika CORATOR_TEST_ACME_DIRECTORY (misal https://localhost:14000/dir)
+// dan CORATOR_TEST_ACME_CA (sertifikat CA HTTPS pebble) diisi. Jalankan pebble dengan
+// PEBBLE_VA_ALWAYS_VALID=1 agar challenge tidak perlu dijangkau dari pebble.
+func TestACMEIssuanceWithPebble(t *testing.T) {
+	directory, ca := os.Getenv("CORATOR_TEST_ACME_DIRECTORY"), os.Getenv("CORATOR_TEST_ACME_CA")
+	if directory == "" || ca == "" {
+		t.Skip("CORATOR_TEST_ACME_DIRECTORY dan CORATOR_TEST_ACME_CA tidak diisi")
+	}
+
+	cacheDir := t.TempDir()
+	m, err := New(config.TLSConfig{
+		ClientAuth: "none",
+		ACME: config.ACMEConfig{
+			Enable:       true,
+			Domains:      []string{"pebble.corator.test"},
+			Email:        "security@corator.test",
+			DirectoryURL: directory,
+			CacheDir:     cacheDir,
+			CAFile:       ca,
+		},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	if got := servedName(t, m, ecdsaHello("pebble.corator.test")); got != "pebble.corator.test" {
+		t.Errorf("sertifikat ACME diterbitkan untuk %s, seharusnya pebble.corator.test", got)
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, "pebble.corator.test")); err != nil {
+		t.Errorf("sertifikat ACME tidak disimpan di cache: %v", err)
+	}
+}
@@ -2,18 +2,47 @@ package uploader
 
 import (
 	"fmt"
+	"slices"
 
 	"github.com/luhtaf/corator/config"
 )
 
 // NewUploader adalah factory yang membuat instance uploader berdasarkan konfigurasi.
 func NewUploader(cfg *config.Config) (Uploader, error) {
-	switch cfg.Uploader.Type {
+	return NewUploaderByType(cfg.Uploader.Type, cfg.Uploader)
+}
+
+// NewUploaders membuat uploader default diikuti uploader lain yang dipakai route policy.
+// Elemen pertama selalu uploader default.
+func NewUploaders(cfg *config.Config) ([]Uploader, error) {
+	defaultUploader, err := NewUploader(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	uploaders := []Uploader{defaultUploader}
+	for _, route := range cfg.Policy.Routes {
+		if route.Uploader == "" || slices.ContainsFunc(uploaders, func(up Uploader) bool { return up.Name() == route.Uploader }) {
+			continue
+		}
+		up, err := NewUploaderByType(route.Uploader, cfg.Uploader)
+		if err != nil {
+			return nil, fmt.Errorf("gagal membuat uploader %s untuk route %s: %w", route.Uploader, route.Name, err)
+		}
+		uploaders = append(uploaders, up)
+	}
+	return uploaders, nil
+}
+
+// NewUploaderByType membuat uploader dengan tipe tertentu, misalnya untuk route
+// policy yang menyimpan bukti ke tujuan selain uploader default.
+func NewUploaderByType(uploaderType string, cfg config.UploaderConfig) (Uploader, error) {
+	switch uploaderType {
 	case "local":
-		return NewLocalUploader(cfg.Uploader.Local)
+		return NewLocalUploader(cfg.Local)
 	case "s3":
-		return NewS3Uploader(cfg.Uploader.S3)
+		return NewS3Uploader(cfg.S3)
 	default:
-		return nil, fmt.Errorf("tipe uploader tidak dikenal: %s", cfg.Uploader.Type)
+		return nil, fmt.Errorf("tipe uploader tidak dikenal: %s", uploaderType)
 	}
 }
@@ -2,12 +2,15 @@ package uploader
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/luhtaf/corator/config"
+	"github.com/luhtaf/corator/evidence"
 )
 
 // LocalUploader adalah implementasi uploader untuk menyimpan file ke disk lokal.
@@ -25,28 +28,75 @@ func NewLocalUploader(cfg config.LocalConfig) (*LocalUploader, error) {
 	}, nil
 }
 
-// Upload menyimpan file ke path yang telah ditentukan.
-func (u *LocalUploader) Upload(ctx context.Context, fileReader io.Reader, uniqueFilename string) (string, error) {
+// Name mengembalikan nama backend uploader.
+func (u *LocalUploader) Name() string {
+	return "local"
+}
+
+// Check memastikan direktori tujuan ada dan bisa ditulisi.
+func (u *LocalUploader) Check(ctx context.Context) error {
+	if err := os.MkdirAll(u.destinationPath, 0755); err != nil {
+		return fmt.Errorf("gagal membuat direktori tujuan: %w", err)
+	}
+
+	probe, err := os.CreateTemp(u.destinationPath, ".corator-health-*")
+	if err != nil {
+		return fmt.Errorf("direktori tujuan tidak bisa ditulisi: %w", err)
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+// Upload menyimpan file ke path yang telah ditentukan, beserta sidecar
+// "<nama file>.json" yang berisi metadata chain-of-custody.
+func (u *LocalUploader) Upload(ctx context.Context, fileReader io.Reader, uniqueFilename string, meta evidence.Metadata) (string, error) {
 	// Pastikan direktori tujuan ada
 	if err := os.MkdirAll(u.destinationPath, 0755); err != nil {
 		return "", fmt.Errorf("gagal membuat direktori tujuan: %w", err)
 	}
 
 	// Gabungkan path direktori dengan nama file
-	fullPath := filepath.Join(u.destinationPath, uniqueFilename)
+	fullPath, err := u.resolve(uniqueFilename)
+	if err != nil {
+		return "", err
+	}
 
 	// Buat file baru di tujuan
 	dst, err := os.Create(fullPath)
 	if err != nil {
 		return "", fmt.Errorf("gagal membuat file tujuan: %w", err)
 	}
-	defer dst.Close()
 
 	// Salin konten dari file sumber ke file tujuan
 	if _, err := io.Copy(dst, fileReader); err != nil {
+		dst.Close()
 		return "", fmt.Errorf("gagal menyalin konten file: %w", err)
 	}
+	// Error saat menutup bisa berarti data belum sepenuhnya tertulis ke disk
+	if err := dst.Close(); err != nil {
+		return "", fmt.Errorf("gagal menutup file tujuan: %w", err)
+	}
+
+	// Tulis metadata ke file sidecar di samping file bukti
+	sidecar, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("gagal marshal metadata file: %w", err)
+	}
+	if err := os.WriteFile(fullPath+".json", sidecar, 0644); err != nil {
+		return "", fmt.Errorf("gagal menulis metadata file: %w", err)
+	}
 
 	// Kembalikan path lengkap dari file yang berhasil disimpan
 	return fullPath, nil
 }
+
+// resolve menggabungkan direktori tujuan dengan nama file dan menolak nama yang
+// mengarah ke luar direktori tujuan.
+func (u *LocalUploader) resolve(uniqueFilename string) (string, error) {
+	fullPath := filepath.Join(u.destinationPath, uniqueFilename)
+	rel, err := filepath.Rel(u.destinationPath, fullPath)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("nama file %q berada di luar direktori tujuan", uniqueFilename)
+	}
+	return fullPath, nil
+}
@@ -0,0 +1,49 @@
+package uploader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/luhtaf/corator/config"
+	"github.com/luhtaf/corator/evidence"
+)
+
+func TestLocalUploaderRejectsPathOutsideDestination(t *testing.T) {
+	root := t.TempDir()
+	dest := filepath.Join(root, "evidence")
+	u, err := NewLocalUploader(config.LocalConfig{Path: dest})
+	if err != nil {
+		t.Fatalf("NewLocalUploader: %v", err)
+	}
+
+	for _, name := range []string{"req_a/../../../../pwn.png", "../pwn.png", "..", "."} {
+		if _, err := u.Upload(context.Background(), strings.NewReader("x"), name, evidence.Metadata{}); err == nil {
+			t.Errorf("Upload(%q) seharusnya ditolak", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "pwn.png")); !os.IsNotExist(err) {
+		t.Errorf("file bukti tertulis di luar direktori tujuan")
+	}
+}
+
+func TestLocalUploaderWritesInsideDestination(t *testing.T) {
+	dest := t.TempDir()
+	u, err := NewLocalUploader(config.LocalConfig{Path: dest})
+	if err != nil {
+		t.Fatalf("NewLocalUploader: %v", err)
+	}
+
+	path, err := u.Upload(context.Background(), strings.NewReader("x"), "req_base64_avatar.png", evidence.Metadata{})
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if filepath.Dir(path) != dest {
+		t.Errorf("file bukti ditulis ke %s, seharusnya di %s", path, dest)
+	}
+	if _, err := os.Stat(path + ".json"); err != nil {
+		t.Errorf("sidecar metadata tidak ditulis: %v", err)
+	}
+}
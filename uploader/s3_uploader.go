@@ -4,12 +4,17 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/luhtaf/corator/config"
+	"github.com/luhtaf/corator/evidence"
 )
 
 // S3Uploader adalah implementasi uploader untuk S3 compatible storage.
@@ -51,12 +56,26 @@ func NewS3Uploader(cfg config.S3Config) (*S3Uploader, error) {
 	}, nil
 }
 
-// Upload mengunggah file ke bucket S3.
-func (u *S3Uploader) Upload(ctx context.Context, fileReader io.Reader, uniqueFilename string) (string, error) {
+// Name mengembalikan nama backend uploader.
+func (u *S3Uploader) Name() string {
+	return "s3"
+}
+
+// Check memastikan bucket S3 bisa diakses dengan kredensial yang dikonfigurasi.
+func (u *S3Uploader) Check(ctx context.Context) error {
+	if _, err := u.client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(u.bucket)}); err != nil {
+		return fmt.Errorf("bucket S3 %s tidak bisa diakses: %w", u.bucket, err)
+	}
+	return nil
+}
+
+// Upload mengunggah file ke bucket S3 dengan metadata chain-of-custody sebagai object metadata.
+func (u *S3Uploader) Upload(ctx context.Context, fileReader io.Reader, uniqueFilename string, meta evidence.Metadata) (string, error) {
 	_, err := u.client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket: aws.String(u.bucket),
-		Key:    aws.String(uniqueFilename),
-		Body:   fileReader,
+		Bucket:   aws.String(u.bucket),
+		Key:      aws.String(uniqueFilename),
+		Body:     fileReader,
+		Metadata: objectMetadata(meta),
 	})
 
 	if err != nil {
@@ -67,3 +86,32 @@ func (u *S3Uploader) Upload(ctx context.Context, fileReader io.Reader, uniqueFil
 	uploadPath := fmt.Sprintf("s3://%s/%s", u.bucket, uniqueFilename)
 	return uploadPath, nil
 }
+
+// objectMetadata mengubah metadata bukti menjadi user metadata S3 (x-amz-meta-*).
+// Nilai yang bisa berisi karakter non-ASCII di-escape karena header HTTP hanya menerima ASCII.
+func objectMetadata(meta evidence.Metadata) map[string]string {
+	m := map[string]string{
+		"md5":           meta.MD5,
+		"sha1":          meta.SHA1,
+		"sha256":        meta.SHA256,
+		"request-id":    meta.RequestID,
+		"original-name": url.QueryEscape(meta.OriginalName),
+		"source-field":  url.QueryEscape(meta.SourceField),
+		"detector":      meta.Detector,
+		"mime-type":     meta.MimeType,
+		"declared-mime": url.QueryEscape(meta.DeclaredMime),
+		"encoding":      meta.Encoding,
+		"mismatches":    strings.Join(meta.Mismatches, ","),
+		"size":          strconv.FormatInt(meta.Size, 10),
+		"domain":        url.QueryEscape(meta.Domain),
+		"path":          url.QueryEscape(meta.Path),
+		"method":        meta.Method,
+		"remote-addr":   meta.RemoteAddr,
+		"captured-at":   meta.CapturedAt.UTC().Format(time.RFC3339Nano),
+	}
+	if meta.ClientCert != nil {
+		m["client-cert-subject"] = url.QueryEscape(meta.ClientCert.Subject)
+		m["client-cert-fingerprint"] = meta.ClientCert.Fingerprint
+	}
+	return m
+}
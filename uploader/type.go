@@ -3,10 +3,17 @@ package uploader
 import (
 	"context"
 	"io"
+
+	"github.com/luhtaf/corator/evidence"
 )
 
 // Uploader adalah interface umum untuk semua implementasi uploader.
 type Uploader interface {
-	// Mengembalikan URL/path dari file yang diupload dan error
-	Upload(ctx context.Context, fileReader io.Reader, uniqueFilename string) (string, error)
+	// Name mengembalikan nama backend uploader, dipakai untuk log dan metrik.
+	Name() string
+	// Check memastikan storage tujuan bisa dipakai, untuk readiness probe.
+	Check(ctx context.Context) error
+	// Mengembalikan URL/path dari file yang diupload dan error.
+	// Metadata chain-of-custody disimpan bersama file (object metadata atau sidecar).
+	Upload(ctx context.Context, fileReader io.Reader, uniqueFilename string, meta evidence.Metadata) (string, error)
 }
@@ -0,0 +1,126 @@
+package upstream
+
+import (
+	"hash/fnv"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ringReplicas adalah jumlah titik virtual per replika di hash ring, agar distribusi
+// key merata dan hanya sebagian kecil key berpindah saat replika keluar atau masuk.
+const ringReplicas = 100
+
+// ringEntry adalah satu titik virtual di hash ring.
+type ringEntry struct {
+	hash   uint32
+	target *target
+}
+
+// newRing membangun hash ring yang terurut untuk strategi consistent-hash.
+func newRing(targets []*target) []ringEntry {
+	ring := make([]ringEntry, 0, len(targets)*ringReplicas)
+	for _, t := range targets {
+		for i := 0; i < ringReplicas; i++ {
+			ring = append(ring, ringEntry{hash: hashKey(t.url.String() + "#" + strconv.Itoa(i)), target: t})
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool { return ring[i].hash < ring[j].hash })
+	return ring
+}
+
+// pick memilih replika yang tersedia dan belum ada di exclude sesuai strategi pool,
+// atau nil jika tidak ada.
+func (p *Pool) pick(req *http.Request, exclude map[*target]bool) *target {
+	now := time.Now()
+	usable := func(t *target) bool { return !exclude[t] && t.available(now) }
+
+	switch p.cfg.Strategy {
+	case StrategyConsistentHash:
+		return p.pickHash(req, usable)
+	case StrategyLeastConn:
+		return p.pickLeastConn(usable)
+	default:
+		return p.pickRoundRobin(usable)
+	}
+}
+
+// pickRoundRobin memilih replika berikutnya secara bergiliran.
+func (p *Pool) pickRoundRobin(usable func(*target) bool) *target {
+	start := p.next.Add(1)
+	for i := range p.targets {
+		t := p.targets[(start+uint64(i))%uint64(len(p.targets))]
+		if usable(t) {
+			return t
+		}
+	}
+	return nil
+}
+
+// pickLeastConn memilih replika dengan request aktif paling sedikit. Replika dengan
+// jumlah yang sama dipilih bergiliran agar beban tidak menumpuk di replika pertama.
+func (p *Pool) pickLeastConn(usable func(*target) bool) *target {
+	start := p.next.Add(1)
+	var best *target
+	for i := range p.targets {
+		t := p.targets[(start+uint64(i))%uint64(len(p.targets))]
+		if usable(t) && (best == nil || t.active.Load() < best.active.Load()) {
+			best = t
+		}
+	}
+	return best
+}
+
+// pickHash memilih replika pertama searah jarum jam dari hash key request di ring,
+// sehingga key yang sama selalu diarahkan ke replika yang sama selama replika itu tersedia.
+func (p *Pool) pickHash(req *http.Request, usable func(*target) bool) *target {
+	h := hashKey(p.requestKey(req))
+	start := sort.Search(len(p.ring), func(i int) bool { return p.ring[i].hash >= h })
+	for i := range p.ring {
+		entry := p.ring[(start+i)%len(p.ring)]
+		if usable(entry.target) {
+			return entry.target
+		}
+	}
+	return nil
+}
+
+// requestKey mengambil nilai yang di-hash sesuai hash_key: IP client (default),
+// header atau cookie. Jika header atau cookie tidak ada, IP client yang dipakai.
+func (p *Pool) requestKey(req *http.Request) string {
+	kind, name, _ := strings.Cut(p.cfg.HashKey, ":")
+	switch kind {
+	case "header":
+		if v := req.Header.Get(name); v != "" {
+			return v
+		}
+	case "cookie":
+		if c, err := req.Cookie(name); err == nil && c.Value != "" {
+			return c.Value
+		}
+	}
+
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// hashKey menghitung hash FNV-1a 32-bit dari key, diikuti finalizer murmur3. FNV-1a
+// saja hampir tidak mengacak key yang hanya berbeda di karakter terakhir (misal titik
+// virtual "url#1", "url#2"), sehingga titik satu replika berkumpul dan beban tidak merata.
+func hashKey(key string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	x := h.Sum32()
+	x ^= x >> 16
+	x *= 0x85ebca6b
+	x ^= x >> 13
+	x *= 0xc2b2ae35
+	x ^= x >> 16
+	return x
+}
@@ -0,0 +1,32 @@
+package upstream
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"testing"
+)
+
+func TestRingSpreadsKeysEvenly(t *testing.T) {
+	var targets []*target
+	for _, raw := range []string{"http://127.0.0.1:41001", "http://127.0.0.1:41002", "http://127.0.0.1:41003"} {
+		u, _ := url.Parse(raw)
+		targets = append(targets, &target{url: u})
+	}
+	ring := newRing(targets)
+
+	counts := make(map[*target]int)
+	const keys = 3000
+	for i := 0; i < keys; i++ {
+		h := hashKey(fmt.Sprintf("tenant-%d", i))
+		idx := sort.Search(len(ring), func(j int) bool { return ring[j].hash >= h })
+		counts[ring[idx%len(ring)].target]++
+	}
+
+	// Setiap replika seharusnya mendapat sekitar sepertiga key
+	for _, tg := range targets {
+		if share := float64(counts[tg]) / keys; share < 0.25 || share > 0.42 {
+			t.Errorf("replika %s mendapat %.0f%% key", tg.url.Host, share*100)
+		}
+	}
+}
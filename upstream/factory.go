@@ -0,0 +1,79 @@
+package upstream
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/luhtaf/corator/config"
+)
+
+// NewPools membuat pool untuk setiap upstream di konfigurasi, ditambah pool satu target
+// untuk backend default (SERVER_BACKEND_URL, jika SERVER_UPSTREAM kosong) dan backend
+// route yang ditulis sebagai URL. Key map adalah
+// nama upstream atau URL backend-nya. Semua pool berbagi satu transport dari PROXY_*.
+// Health check aktif baru berjalan setelah Start.
+func NewPools(cfg *config.Config) (map[string]*Pool, error) {
+	transport, err := NewTransport(cfg.Proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	pools := make(map[string]*Pool)
+	for _, upCfg := range cfg.Upstreams {
+		pool, err := New(upCfg, transport)
+		if err != nil {
+			return nil, err
+		}
+		pools[upCfg.Name] = pool
+	}
+
+	var backends []string
+	if cfg.Server.Upstream == "" {
+		backends = append(backends, cfg.Server.BackendURL)
+	}
+	for _, route := range cfg.Policy.Routes {
+		if route.Backend != "" {
+			backends = append(backends, route.Backend)
+		}
+	}
+	for _, raw := range backends {
+		if pools[raw] != nil {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("URL backend tidak valid: %q", raw)
+		}
+		if pools[raw], err = NewSingle(u, transport); err != nil {
+			return nil, err
+		}
+	}
+	return pools, nil
+}
+
+// ClosePools menutup semua pool hasil NewPools, lalu menutup koneksi idle transport
+// yang mereka pakai bersama. Pool dari NewPools lain memakai transport sendiri sehingga
+// tidak terpengaruh.
+func ClosePools(pools map[string]*Pool) {
+	closed := make(map[http.RoundTripper]bool)
+	for _, pool := range pools {
+		pool.Close()
+		if closed[pool.transport] {
+			continue
+		}
+		closed[pool.transport] = true
+		if t, ok := pool.transport.(interface{ CloseIdleConnections() }); ok {
+			t.CloseIdleConnections()
+		}
+	}
+}
+
+// DefaultKey mengembalikan key pool default di hasil NewPools: SERVER_UPSTREAM jika
+// diisi, selain itu SERVER_BACKEND_URL.
+func DefaultKey(cfg *config.Config) string {
+	if cfg.Server.Upstream != "" {
+		return cfg.Server.Upstream
+	}
+	return cfg.Server.BackendURL
+}
@@ -0,0 +1,397 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/luhtaf/corator/config"
+	"github.com/luhtaf/corator/metrics"
+)
+
+// Strategi load balancing yang didukung.
+const (
+	StrategyRoundRobin     = "round-robin"
+	StrategyLeastConn      = "least-conn"
+	StrategyConsistentHash = "consistent-hash"
+)
+
+// Nilai default untuk pengaturan pool yang tidak diisi.
+const (
+	defaultFailTimeout        = 30 * time.Second
+	defaultCheckInterval      = 10 * time.Second
+	defaultCheckTimeout       = 2 * time.Second
+	defaultHealthyThreshold   = 2
+	defaultUnhealthyThreshold = 3
+)
+
+// ErrNoTarget dikembalikan jika semua replika sedang mati atau dikeluarkan.
+var ErrNoTarget = errors.New("tidak ada replika upstream yang sehat")
+
+// Pool adalah sekumpulan replika backend yang dipakai sebagai http.RoundTripper oleh
+// reverse proxy. Setiap percobaan memilih replika dengan strategi pool; request
+// idempotent dicoba ulang ke replika lain jika gagal.
+type Pool struct {
+	Name string
+
+	cfg       config.UpstreamConfig
+	targets   []*target
+	ring      []ringEntry // Hanya untuk consistent-hash
+	next      atomic.Uint64
+	transport http.RoundTripper
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+// New membuat pool dari konfigurasi upstream yang mengirim request lewat transport.
+// Health check aktif baru berjalan setelah Start.
+func New(cfg config.UpstreamConfig, transport http.RoundTripper) (*Pool, error) {
+	if len(cfg.Targets) == 0 {
+		return nil, fmt.Errorf("upstream %s tidak memiliki target", cfg.Name)
+	}
+	if cfg.Strategy == "" {
+		cfg.Strategy = StrategyRoundRobin
+	}
+	if cfg.FailTimeout == 0 {
+		cfg.FailTimeout = defaultFailTimeout
+	}
+	if cfg.HealthCheck.Interval == 0 {
+		cfg.HealthCheck.Interval = defaultCheckInterval
+	}
+	if cfg.HealthCheck.Timeout == 0 {
+		cfg.HealthCheck.Timeout = defaultCheckTimeout
+	}
+	if cfg.HealthCheck.HealthyThreshold == 0 {
+		cfg.HealthCheck.HealthyThreshold = defaultHealthyThreshold
+	}
+	if cfg.HealthCheck.UnhealthyThreshold == 0 {
+		cfg.HealthCheck.UnhealthyThreshold = defaultUnhealthyThreshold
+	}
+
+	p := &Pool{
+		Name:      cfg.Name,
+		cfg:       cfg,
+		transport: transport,
+		stop:      make(chan struct{}),
+	}
+	for _, raw := range cfg.Targets {
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("target upstream %s tidak valid: %q", cfg.Name, raw)
+		}
+		t := &target{url: u}
+		t.healthy.Store(true)
+		p.targets = append(p.targets, t)
+		metrics.SetUpstreamTargetUp(p.Name, u.Host, true)
+	}
+
+	switch cfg.Strategy {
+	case StrategyRoundRobin, StrategyLeastConn:
+	case StrategyConsistentHash:
+		p.ring = newRing(p.targets)
+	default:
+		return nil, fmt.Errorf("strategi upstream %s tidak dikenal: %s", cfg.Name, cfg.Strategy)
+	}
+	return p, nil
+}
+
+// NewSingle membuat pool dengan satu target tanpa health check dan ejeksi, untuk
+// backend yang ditulis sebagai URL biasa. Nama pool memakai URL dengan password
+// disamarkan karena dipakai di log dan label metrik.
+func NewSingle(target *url.URL, transport http.RoundTripper) (*Pool, error) {
+	return New(config.UpstreamConfig{Name: target.Redacted(), Targets: []string{target.String()}}, transport)
+}
+
+// Start menjalankan health check aktif jika path health check diisi.
+func (p *Pool) Start() {
+	if p.cfg.HealthCheck.Path == "" {
+		return
+	}
+	for _, t := range p.targets {
+		p.wg.Add(1)
+		go p.healthCheck(t)
+	}
+}
+
+// Close menghentikan health check aktif. Request yang sedang berjalan tidak terpengaruh.
+// Koneksi idle tidak ditutup karena transport dipakai bersama pool lain; gunakan
+// ClosePools untuk menutup satu set pool beserta transport-nya.
+func (p *Pool) Close() {
+	p.once.Do(func() { close(p.stop) })
+	p.wg.Wait()
+}
+
+// RoundTrip meneruskan request ke replika yang dipilih strategi pool. Error koneksi dan
+// response 502/503/504 dihitung sebagai kegagalan replika; request idempotent yang
+// body-nya bisa dibaca ulang dicoba lagi ke replika lain hingga Retries kali.
+func (p *Pool) RoundTrip(req *http.Request) (*http.Response, error) {
+	retryable := isIdempotent(req.Method) && (req.Body == nil || req.Body == http.NoBody || req.GetBody != nil)
+	tried := make(map[*target]bool, len(p.targets))
+
+	t := p.pick(req, tried)
+	if t == nil {
+		return nil, fmt.Errorf("upstream %s: %w", p.Name, ErrNoTarget)
+	}
+
+	for attempt := 0; ; attempt++ {
+		tried[t] = true
+
+		outreq, err := p.prepare(req, t, attempt)
+		if err != nil {
+			return nil, err
+		}
+
+		t.active.Add(1)
+		resp, err := p.transport.RoundTrip(outreq)
+		failed := err != nil || isUpstreamFailure(resp.StatusCode)
+
+		// Request yang dibatalkan client bukan kesalahan replika
+		canceled := req.Context().Err() != nil
+		if !canceled {
+			if failed {
+				p.markFailure(t)
+			} else {
+				t.fails.Store(0)
+			}
+		}
+
+		// Cari replika lain yang belum dicoba jika request boleh diulang
+		var next *target
+		if failed && retryable && !canceled && attempt < p.cfg.Retries {
+			next = p.pick(req, tried)
+		}
+		if next == nil {
+			if err != nil {
+				t.active.Add(-1)
+				return nil, err
+			}
+			resp.Body = &trackedBody{ReadCloser: resp.Body, target: t}
+			return resp, nil
+		}
+
+		if err != nil {
+			log.Printf("Upstream %s: replika %s gagal, mencoba %s: %v", p.Name, t.url.Host, next.url.Host, err)
+		} else {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+			resp.Body.Close()
+			log.Printf("Upstream %s: replika %s merespons %d, mencoba %s", p.Name, t.url.Host, resp.StatusCode, next.url.Host)
+		}
+		t.active.Add(-1)
+		t = next
+	}
+}
+
+// prepare membuat salinan request dengan scheme, host dan path replika tujuan.
+func (p *Pool) prepare(req *http.Request, t *target, attempt int) (*http.Request, error) {
+	outreq := req.Clone(req.Context())
+	if attempt > 0 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("gagal membaca ulang body request: %w", err)
+		}
+		outreq.Body = body
+	}
+
+	outreq.URL.Scheme = t.url.Scheme
+	outreq.URL.Host = t.url.Host
+	outreq.URL.Path, outreq.URL.RawPath = joinURLPath(t.url, req.URL)
+	if t.url.RawQuery != "" {
+		if outreq.URL.RawQuery == "" {
+			outreq.URL.RawQuery = t.url.RawQuery
+		} else {
+			outreq.URL.RawQuery = t.url.RawQuery + "&" + outreq.URL.RawQuery
+		}
+	}
+	return outreq, nil
+}
+
+// markFailure mencatat satu kegagalan pasif dan mengeluarkan replika setelah MaxFails
+// kegagalan berturut-turut.
+func (p *Pool) markFailure(t *target) {
+	if p.cfg.MaxFails <= 0 {
+		return
+	}
+	if t.fails.Add(1) < int32(p.cfg.MaxFails) {
+		return
+	}
+	t.fails.Store(0)
+	t.ejectedUntil.Store(time.Now().Add(p.cfg.FailTimeout).UnixNano())
+	metrics.ObserveUpstreamEjection(p.Name, t.url.Host)
+	log.Printf("PERINGATAN: Upstream %s: replika %s dikeluarkan selama %s setelah %d error berturut-turut",
+		p.Name, t.url.Host, p.cfg.FailTimeout, p.cfg.MaxFails)
+}
+
+// healthCheck memeriksa satu replika secara berkala hingga pool ditutup.
+func (p *Pool) healthCheck(t *target) {
+	defer p.wg.Done()
+
+	client := &http.Client{
+		Timeout:   p.cfg.HealthCheck.Timeout,
+		Transport: p.transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	checkURL := *t.url
+	checkURL.Path, checkURL.RawPath = joinURLPath(t.url, &url.URL{Path: p.cfg.HealthCheck.Path})
+
+	ticker := time.NewTicker(p.cfg.HealthCheck.Interval)
+	defer ticker.Stop()
+
+	var successes, failures int
+	for {
+		err := probe(client, checkURL.String())
+		if err == nil {
+			successes, failures = successes+1, 0
+			if !t.healthy.Load() && successes >= p.cfg.HealthCheck.HealthyThreshold {
+				t.healthy.Store(true)
+				metrics.SetUpstreamTargetUp(p.Name, t.url.Host, true)
+				log.Printf("Upstream %s: replika %s kembali sehat", p.Name, t.url.Host)
+			}
+		} else {
+			successes, failures = 0, failures+1
+			if t.healthy.Load() && failures >= p.cfg.HealthCheck.UnhealthyThreshold {
+				t.healthy.Store(false)
+				metrics.SetUpstreamTargetUp(p.Name, t.url.Host, false)
+				log.Printf("PERINGATAN: Upstream %s: replika %s tidak sehat: %v", p.Name, t.url.Host, err)
+			}
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probe mengirim satu request health check; status 2xx dan 3xx dianggap sehat.
+func probe(client *http.Client, checkURL string) error {
+	resp, err := client.Get(checkURL)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Check memastikan minimal satu replika tersedia dan bisa dihubungi lewat TCP,
+// untuk pemeriksaan readiness.
+func (p *Pool) Check(ctx context.Context) error {
+	var errs []error
+	for _, t := range p.targets {
+		if !t.available(time.Now()) {
+			continue
+		}
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", hostPort(t.url))
+		if err == nil {
+			return conn.Close()
+		}
+		errs = append(errs, fmt.Errorf("gagal terhubung ke %s: %w", t.url.Host, err))
+	}
+	if len(errs) == 0 {
+		return ErrNoTarget
+	}
+	return errors.Join(errs...)
+}
+
+// target adalah satu replika di dalam pool.
+type target struct {
+	url          *url.URL
+	active       atomic.Int64 // Request yang sedang berjalan, untuk least-conn
+	healthy      atomic.Bool  // Hasil health check aktif
+	fails        atomic.Int32 // Kegagalan pasif berturut-turut
+	ejectedUntil atomic.Int64 // Unix nano, replika dilewati hingga waktu ini
+}
+
+// available mengembalikan true jika replika sehat dan tidak sedang dikeluarkan.
+func (t *target) available(now time.Time) bool {
+	return t.healthy.Load() && now.UnixNano() >= t.ejectedUntil.Load()
+}
+
+// trackedBody mengurangi jumlah koneksi aktif replika saat body response ditutup.
+type trackedBody struct {
+	io.ReadCloser
+	target *target
+	once   sync.Once
+}
+
+func (b *trackedBody) Close() error {
+	b.once.Do(func() { b.target.active.Add(-1) })
+	return b.ReadCloser.Close()
+}
+
+// isIdempotent mengembalikan true untuk method yang aman dikirim ulang.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// isUpstreamFailure mengembalikan true untuk status yang menandakan replika bermasalah.
+func isUpstreamFailure(status int) bool {
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+// joinURLPath menggabungkan path target dengan path request, seperti
+// httputil.NewSingleHostReverseProxy.
+func joinURLPath(a, b *url.URL) (path, rawpath string) {
+	if a.RawPath == "" && b.RawPath == "" {
+		return singleJoiningSlash(a.Path, b.Path), ""
+	}
+	apath := a.EscapedPath()
+	bpath := b.EscapedPath()
+
+	aslash := strings.HasSuffix(apath, "/")
+	bslash := strings.HasPrefix(bpath, "/")
+
+	switch {
+	case aslash && bslash:
+		return a.Path + b.Path[1:], apath + bpath[1:]
+	case !aslash && !bslash:
+		return a.Path + "/" + b.Path, apath + "/" + bpath
+	}
+	return a.Path + b.Path, apath + bpath
+}
+
+func singleJoiningSlash(a, b string) string {
+	aslash := strings.HasSuffix(a, "/")
+	bslash := strings.HasPrefix(b, "/")
+	switch {
+	case aslash && bslash:
+		return a + b[1:]
+	case !aslash && !bslash:
+		return a + "/" + b
+	}
+	return a + b
+}
+
+// hostPort mengembalikan alamat host:port URL, dengan port default sesuai scheme.
+func hostPort(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	port := "80"
+	if u.Scheme == "https" {
+		port = "443"
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
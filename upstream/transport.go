@@ -0,0 +1,69 @@
+package upstream
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/luhtaf/corator/config"
+)
+
+// NewTransport membuat http.Transport yang dipakai bersama oleh semua pool, sehingga
+// koneksi ke backend di-pool dan dipakai ulang antar request.
+func NewTransport(cfg config.ProxyConfig) (*http.Transport, error) {
+	dialer := &net.Dialer{
+		Timeout:   cfg.DialTimeout,
+		KeepAlive: cfg.KeepAlive,
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.TLSInsecureSkipVerify}
+	if cfg.TLSCAFile != "" {
+		pem, err := os.ReadFile(cfg.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("gagal membaca CA backend %s: %w", cfg.TLSCAFile, err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("file CA backend %s tidak berisi sertifikat PEM", cfg.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   cfg.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: cfg.ResponseHeaderTimeout,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		ExpectContinueTimeout: time.Second,
+	}
+
+	protocols := new(http.Protocols)
+	switch cfg.HTTP2 {
+	case "", "auto":
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+	case "off":
+		protocols.SetHTTP1(true)
+	case "h2c":
+		// Tanpa HTTP/1, backend http:// dihubungi dengan HTTP/2 prior knowledge
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+	default:
+		return nil, fmt.Errorf("mode HTTP/2 backend tidak dikenal: %s", cfg.HTTP2)
+	}
+	transport.Protocols = protocols
+
+	return transport, nil
+}
@@ -30,3 +30,26 @@ func NewWAF(cfg config.WAFConfig) (coraza.WAF, error) {
 
 	return waf, nil
 }
+
+// NewWAFs membuat WAF global dan WAF untuk setiap rule set khusus route, dengan key
+// path konfigurasi Coraza-nya. Route yang memakai file yang sama berbagi satu WAF.
+func NewWAFs(cfg *config.Config) (map[string]coraza.WAF, error) {
+	global, err := NewWAF(cfg.WAF)
+	if err != nil {
+		return nil, err
+	}
+
+	wafs := map[string]coraza.WAF{cfg.WAF.CorazaConfigPath: global}
+	for _, route := range cfg.Policy.Routes {
+		path := route.CorazaConfigPath
+		if path == "" || wafs[path] != nil {
+			continue
+		}
+		routeWAF, err := NewWAF(config.WAFConfig{CorazaConfigPath: path})
+		if err != nil {
+			return nil, fmt.Errorf("rule set %s untuk route %s: %w", path, route.Name, err)
+		}
+		wafs[path] = routeWAF
+	}
+	return wafs, nil
+}
@@ -0,0 +1,262 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"github.com/luhtaf/corator/config"
+)
+
+// Kebijakan saat antrean pool penuh.
+const (
+	OverflowBlock      = "block"       // Tunggu hingga ada slot kosong (backpressure ke request)
+	OverflowDropOldest = "drop-oldest" // Buang job tertua di antrean
+	OverflowSpill      = "spill"       // Simpan job ke disk lewat Job.Spill
+)
+
+// Job adalah satu unit pekerjaan di pool. Untuk setiap job, pool memanggil tepat
+// satu dari Run, Spill atau Discard, dan job bertanggung jawab melepaskan resource-nya.
+type Job interface {
+	// Run menjalankan job di salah satu worker.
+	Run(ctx context.Context)
+	// Spill menyimpan job ke disk saat antrean penuh dengan kebijakan "spill".
+	Spill() error
+	// Discard membuang job yang tidak akan dijalankan.
+	Discard()
+}
+
+// Stats adalah snapshot metrik pool.
+type Stats struct {
+	Workers   int
+	QueueSize int
+	Queued    int
+	Active    int64
+	Submitted int64
+	Completed int64
+	Dropped   int64
+	Spilled   int64
+}
+
+// Pool adalah worker pool dengan jumlah worker dan ukuran antrean terbatas,
+// dipakai bersama oleh tahap upload dan logging.
+type Pool struct {
+	jobs     chan Job
+	workers  int
+	overflow string
+
+	// ctx dibatalkan saat batas waktu shutdown habis agar job yang berjalan berhenti.
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// closing ditutup saat Shutdown dimulai agar Submit yang menunggu antrean kosong
+	// berhenti menunggu. Channel jobs tidak pernah ditutup; mu melindungi closed dan
+	// submits hanya sebentar, tidak selama Submit menunggu.
+	closing   chan struct{}
+	closeOnce sync.Once
+	mu        sync.Mutex
+	closed    bool
+	submits   sync.WaitGroup
+
+	active    atomic.Int64
+	submitted atomic.Int64
+	completed atomic.Int64
+	dropped   atomic.Int64
+	spilled   atomic.Int64
+}
+
+// NewPool membuat pool baru dan langsung menjalankan worker-nya.
+func NewPool(cfg config.WorkerConfig) (*Pool, error) {
+	if cfg.Count <= 0 {
+		return nil, fmt.Errorf("jumlah worker harus lebih dari 0")
+	}
+	if cfg.QueueSize < 0 {
+		return nil, fmt.Errorf("ukuran antrean worker tidak boleh negatif")
+	}
+
+	switch cfg.OverflowPolicy {
+	case OverflowBlock, OverflowDropOldest, OverflowSpill:
+	default:
+		return nil, fmt.Errorf("kebijakan overflow worker tidak dikenal: %s", cfg.OverflowPolicy)
+	}
+	// Tanpa antrean tidak ada job tertua yang bisa dibuang, dan Submit akan berputar terus
+	if cfg.OverflowPolicy == OverflowDropOldest && cfg.QueueSize == 0 {
+		return nil, fmt.Errorf("kebijakan overflow %s membutuhkan ukuran antrean lebih dari 0", OverflowDropOldest)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool{
+		jobs:     make(chan Job, cfg.QueueSize),
+		closing:  make(chan struct{}),
+		workers:  cfg.Count,
+		overflow: cfg.OverflowPolicy,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+
+	p.wg.Add(cfg.Count)
+	for i := 0; i < cfg.Count; i++ {
+		go p.work()
+	}
+
+	return p, nil
+}
+
+// work mengambil job dari antrean dan menjalankannya. Setelah Shutdown dimulai,
+// worker menghabiskan sisa antrean lalu berhenti.
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for {
+		select {
+		case job := <-p.jobs:
+			p.run(job)
+		case <-p.closing:
+			for {
+				select {
+				case job := <-p.jobs:
+					p.run(job)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// run menjalankan satu job dan mencatat metriknya.
+func (p *Pool) run(job Job) {
+	p.active.Add(1)
+	job.Run(p.ctx)
+	p.active.Add(-1)
+	p.completed.Add(1)
+}
+
+// Submit memasukkan job ke antrean sesuai kebijakan overflow.
+func (p *Pool) Submit(job Job) {
+	p.submitted.Add(1)
+
+	p.mu.Lock()
+	closed := p.closed
+	if !closed {
+		p.submits.Add(1)
+	}
+	p.mu.Unlock()
+
+	// Pool sudah ditutup, job langsung disimpan ke disk
+	if closed {
+		p.spill(job)
+		return
+	}
+	defer p.submits.Done()
+
+	switch p.overflow {
+	case OverflowDropOldest:
+		for {
+			select {
+			case p.jobs <- job:
+				return
+			case <-p.closing:
+				p.spill(job)
+				return
+			default:
+			}
+
+			// Antrean penuh, buang job tertua untuk memberi tempat
+			select {
+			case old := <-p.jobs:
+				old.Discard()
+				p.dropped.Add(1)
+			default:
+			}
+		}
+
+	case OverflowSpill:
+		select {
+		case p.jobs <- job:
+		default:
+			p.spill(job)
+		}
+
+	default:
+		// Tunggu slot kosong tanpa memegang lock; jika Shutdown dimulai selama
+		// menunggu, job disimpan ke disk
+		select {
+		case p.jobs <- job:
+		case <-p.closing:
+			p.spill(job)
+		}
+	}
+}
+
+// spill menyimpan job ke disk, atau membuangnya jika gagal.
+func (p *Pool) spill(job Job) {
+	if err := job.Spill(); err != nil {
+		log.Printf("PERINGATAN: Job gagal disimpan ke disk: %v", err)
+		job.Discard()
+		p.dropped.Add(1)
+		return
+	}
+	p.spilled.Add(1)
+}
+
+// Shutdown berhenti menerima job baru dan menunggu semua job di antrean selesai.
+// Jika ctx habis lebih dulu, job yang sedang berjalan dibatalkan dan sisa antrean
+// disimpan ke disk lewat Job.Spill. Job yang di-submit setelah Shutdown juga di-spill.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	// Bangunkan Submit yang sedang menunggu sebelum mengambil lock
+	p.closeOnce.Do(func() { close(p.closing) })
+
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.submits.Wait()
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		// Job dari Submit yang bersamaan dengan Shutdown bisa masuk setelah worker berhenti
+		p.spillQueued()
+		return nil
+	case <-ctx.Done():
+	}
+
+	// Batas waktu habis: batalkan job yang berjalan dan simpan sisa antrean ke disk
+	p.cancel()
+	p.submits.Wait()
+	p.spillQueued()
+	return fmt.Errorf("batas waktu drain habis, %d job masih berjalan: %w", p.active.Load(), ctx.Err())
+}
+
+// spillQueued menyimpan semua job yang masih di antrean ke disk.
+func (p *Pool) spillQueued() {
+	for {
+		select {
+		case job := <-p.jobs:
+			p.spill(job)
+		default:
+			return
+		}
+	}
+}
+
+// Stats mengembalikan snapshot metrik pool.
+func (p *Pool) Stats() Stats {
+	return Stats{
+		Workers:   p.workers,
+		QueueSize: cap(p.jobs),
+		Queued:    len(p.jobs),
+		Active:    p.active.Load(),
+		Submitted: p.submitted.Load(),
+		Completed: p.completed.Load(),
+		Dropped:   p.dropped.Load(),
+		Spilled:   p.spilled.Load(),
+	}
+}
@@ -0,0 +1,144 @@
+package worker
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/luhtaf/corator/config"
+)
+
+// blockingJob berjalan sampai context pool dibatalkan.
+type blockingJob struct {
+	started chan struct{}
+	spilled *atomic.Int64
+}
+
+func (j blockingJob) Run(ctx context.Context) {
+	if j.started != nil {
+		close(j.started)
+	}
+	<-ctx.Done()
+}
+
+func (j blockingJob) Spill() error {
+	j.spilled.Add(1)
+	return nil
+}
+
+func (j blockingJob) Discard() {}
+
+// countingJob mencatat apakah job dijalankan atau dibuang.
+type countingJob struct {
+	ran, discarded *atomic.Int64
+}
+
+func (j countingJob) Run(context.Context) { j.ran.Add(1) }
+func (j countingJob) Spill() error        { return nil }
+func (j countingJob) Discard()            { j.discarded.Add(1) }
+
+func TestShutdownDoesNotDeadlockOnBlockedSubmit(t *testing.T) {
+	p, err := NewPool(config.WorkerConfig{Count: 1, QueueSize: 1, OverflowPolicy: OverflowBlock})
+	if err != nil {
+		t.Fatalf("NewPool: %v", err)
+	}
+
+	var spilled atomic.Int64
+	started := make(chan struct{})
+	p.Submit(blockingJob{started: started, spilled: &spilled})
+	<-started
+	p.Submit(blockingJob{spilled: &spilled}) // Mengisi antrean
+
+	// Antrean penuh, Submit ini menunggu slot kosong
+	submitted := make(chan struct{})
+	go func() {
+		p.Submit(blockingJob{spilled: &spilled})
+		close(submitted)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- p.Shutdown(ctx) }()
+
+	select {
+	case err := <-shutdown:
+		if err == nil {
+			t.Errorf("Shutdown seharusnya melaporkan batas waktu habis")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Shutdown deadlock dengan Submit yang menunggu antrean")
+	}
+	<-submitted
+
+	// Job di antrean dan job yang menunggu antrean disimpan ke disk
+	if got := spilled.Load(); got != 2 {
+		t.Errorf("jumlah job yang di-spill = %d, seharusnya 2", got)
+	}
+}
+
+func TestSubmitAfterShutdownSpills(t *testing.T) {
+	p, err := NewPool(config.WorkerConfig{Count: 1, QueueSize: 1, OverflowPolicy: OverflowBlock})
+	if err != nil {
+		t.Fatalf("NewPool: %v", err)
+	}
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	var spilled atomic.Int64
+	p.Submit(blockingJob{spilled: &spilled})
+	if spilled.Load() != 1 {
+		t.Errorf("job yang di-submit setelah Shutdown seharusnya di-spill")
+	}
+}
+
+func TestDropOldestRequiresQueue(t *testing.T) {
+	if _, err := NewPool(config.WorkerConfig{Count: 1, QueueSize: 0, OverflowPolicy: OverflowDropOldest}); err == nil {
+		t.Error("NewPool seharusnya menolak drop-oldest tanpa antrean")
+	}
+}
+
+func TestDropOldestDiscardsOldestQueuedJob(t *testing.T) {
+	p, err := NewPool(config.WorkerConfig{Count: 1, QueueSize: 1, OverflowPolicy: OverflowDropOldest})
+	if err != nil {
+		t.Fatalf("NewPool: %v", err)
+	}
+
+	var spilled atomic.Int64
+	started := make(chan struct{})
+	p.Submit(blockingJob{started: started, spilled: &spilled})
+	<-started
+
+	var oldRan, oldDiscarded, newRan, newDiscarded atomic.Int64
+	p.Submit(countingJob{ran: &oldRan, discarded: &oldDiscarded})
+
+	// Antrean penuh dan worker sibuk, Submit harus kembali dengan membuang job tertua
+	submitted := make(chan struct{})
+	go func() {
+		p.Submit(countingJob{ran: &newRan, discarded: &newDiscarded})
+		close(submitted)
+	}()
+	select {
+	case <-submitted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Submit drop-oldest tidak kembali saat antrean penuh")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	p.Shutdown(ctx)
+
+	if oldDiscarded.Load() != 1 || oldRan.Load() != 0 {
+		t.Errorf("job tertua: dibuang %d, dijalankan %d; seharusnya dibuang", oldDiscarded.Load(), oldRan.Load())
+	}
+	if newDiscarded.Load() != 0 {
+		t.Errorf("job terbaru ikut dibuang")
+	}
+	if got := p.Stats().Dropped; got != 1 {
+		t.Errorf("Stats().Dropped = %d, seharusnya 1", got)
+	}
+}